@@ -0,0 +1,273 @@
+// Package alarm monitors Smoke+CO Alarms and notifies when their smoke or CO
+// alarm state changes. Warnings can be debounced, emergencies and unresolved
+// warnings are escalated to additional notifiers, and changes caused by a
+// manual test are suppressed.
+package alarm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/notify"
+)
+
+// Kind is the kind of alarm raised by a Smoke+CO Alarm.
+type Kind string
+
+// Alarm kinds
+const (
+	Smoke Kind = "smoke"
+	CO    Kind = "co"
+)
+
+// Alert describes an alarm state change. Previous is the state last notified.
+// Escalated is set on alerts sent to escalation notifiers.
+type Alert struct {
+	DeviceID  string
+	Name      string
+	Kind      Kind
+	State     device.AlarmState
+	Previous  device.AlarmState
+	Escalated bool
+	Time      time.Time
+}
+
+func (a Alert) String() string {
+	name := a.Name
+	if name == "" {
+		name = a.DeviceID
+	}
+	if a.State == device.AlarmStateOK {
+		return fmt.Sprintf("%s alarm cleared: %s", a.Kind, name)
+	}
+	return fmt.Sprintf("%s alarm %s: %s", a.Kind, a.State, name)
+}
+
+// Message describes the alert for notifiers. Emergencies are critical,
+// warnings are warnings and cleared alarms are informational.
+func (a Alert) Message() notify.Message {
+	severity := notify.Info
+	switch a.State {
+	case device.AlarmStateEmergency:
+		severity = notify.Critical
+	case device.AlarmStateWarning:
+		severity = notify.Warning
+	}
+	fields := map[string]string{
+		"kind":  string(a.Kind),
+		"state": string(a.State),
+	}
+	if a.Previous != "" {
+		fields["previous"] = string(a.Previous)
+	}
+	if a.Escalated {
+		fields["escalated"] = "true"
+	}
+	return notify.Message{
+		Title:    a.String(),
+		Body:     a.String(),
+		Severity: severity,
+		DeviceID: a.DeviceID,
+		Time:     a.Time,
+		Fields:   fields,
+	}
+}
+
+// Policy configures when alerts are sent.
+type Policy struct {
+	// Debounce is how long a warning must persist before it is notified.
+	// Emergencies are never debounced.
+	Debounce time.Duration
+	// EscalateAfter is how long a warning may remain unresolved before it is
+	// escalated. Zero disables escalating warnings.
+	EscalateAfter time.Duration
+	// Escalation notifiers receive emergencies and escalated warnings in
+	// addition to the monitor's notifiers.
+	Escalation []notify.Notifier
+}
+
+type alarmKey struct {
+	deviceID string
+	kind     Kind
+}
+
+type tracked struct {
+	name      string
+	state     device.AlarmState
+	since     time.Time
+	notified  device.AlarmState
+	escalated bool
+	testing   bool
+}
+
+// Monitor tracks the smoke and CO alarm state of Smoke+CO Alarms.
+type Monitor struct {
+	policy    Policy
+	notifiers []notify.Notifier
+	now       func() time.Time
+
+	mu     sync.Mutex
+	alarms map[alarmKey]*tracked
+}
+
+// NewMonitor creates a monitor that sends alerts to notifiers according to policy.
+func NewMonitor(policy Policy, notifiers ...notify.Notifier) *Monitor {
+	return &Monitor{
+		policy:    policy,
+		notifiers: notifiers,
+		now:       time.Now,
+		alarms:    map[alarmKey]*tracked{},
+	}
+}
+
+// Observe records the state of a Smoke+CO Alarm at the given time and sends
+// any alerts that are due. While a manual test is active, state changes are
+// recorded but not notified.
+func (m *Monitor) Observe(ctx context.Context, sa *device.SmokeAlarm, at time.Time) error {
+	m.mu.Lock()
+	var due []delivery
+	for _, k := range []struct {
+		kind  Kind
+		state device.AlarmState
+	}{{Smoke, sa.SmokeAlarmState}, {CO, sa.CoAlarmState}} {
+		if k.state == "" {
+			continue
+		}
+		tr := m.track(sa.DeviceID, k.kind)
+		if sa.NameLong != "" {
+			tr.name = sa.NameLong
+		}
+		tr.testing = sa.IsManualTestActive
+		if k.state != tr.state {
+			tr.state = k.state
+			tr.since = at
+			tr.escalated = false
+		}
+		due = append(due, m.evaluate(sa.DeviceID, k.kind, tr, at)...)
+	}
+	m.mu.Unlock()
+	return m.deliver(ctx, due)
+}
+
+// Tick sends debounced and escalated alerts that have become due without a
+// new state change, and retries alerts that failed to send.
+func (m *Monitor) Tick(ctx context.Context, at time.Time) error {
+	m.mu.Lock()
+	var due []delivery
+	for key, tr := range m.alarms {
+		due = append(due, m.evaluate(key.deviceID, key.kind, tr, at)...)
+	}
+	m.mu.Unlock()
+	return m.deliver(ctx, due)
+}
+
+// Run observes Smoke+CO Alarm events from a Stream and calls Tick every
+// interval until events is closed or ctx is done. Notifier errors are logged.
+func (m *Monitor) Run(ctx context.Context, events <-chan nest.Event, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			err = m.Tick(ctx, m.now())
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			et, id, data, eventErr := event.GetEvent()
+			sa, isAlarm := data.(*device.SmokeAlarm)
+			if eventErr != nil || et != nest.SmokeCoAlarms || !isAlarm || sa == nil {
+				continue
+			}
+			if sa.DeviceID == "" {
+				sa.DeviceID = id
+			}
+			err = m.Observe(ctx, sa, m.now())
+		}
+		if err != nil {
+			log.Printf("error sending alarm alert: %v\n", err)
+		}
+	}
+}
+
+// delivery is an alert due to be sent to notifiers. The alarm it was evaluated
+// from is marked as notified, or escalated, once the alert is sent.
+type delivery struct {
+	tr        *tracked
+	since     time.Time
+	notifiers []notify.Notifier
+	alert     Alert
+}
+
+// evaluate returns the alerts due for an alarm. It is called with m.mu held.
+func (m *Monitor) evaluate(id string, kind Kind, tr *tracked, at time.Time) []delivery {
+	if tr.testing {
+		return nil
+	}
+	alert := Alert{DeviceID: id, Name: tr.name, Kind: kind, State: tr.state, Previous: tr.notified, Time: at}
+	escalation := alert
+	escalation.Escalated = true
+
+	var due []delivery
+	switch {
+	case tr.state == tr.notified:
+	case tr.state == device.AlarmStateEmergency,
+		tr.state == device.AlarmStateOK || at.Sub(tr.since) >= m.policy.Debounce:
+		due = append(due, delivery{tr: tr, since: tr.since, notifiers: m.notifiers, alert: alert})
+	}
+	if !tr.escalated && (tr.state == device.AlarmStateEmergency ||
+		tr.state == device.AlarmStateWarning && tr.state == tr.notified && m.policy.EscalateAfter > 0 && at.Sub(tr.since) >= m.policy.EscalateAfter) {
+		due = append(due, delivery{tr: tr, since: tr.since, notifiers: m.policy.Escalation, alert: escalation})
+	}
+	return due
+}
+
+// deliver sends alerts without holding m.mu, so a slow notifier does not block
+// other events. Alerts that fail to send are retried on the next Tick.
+func (m *Monitor) deliver(ctx context.Context, due []delivery) error {
+	var firstErr error
+	for _, d := range due {
+		if err := send(ctx, d.notifiers, d.alert); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		m.mu.Lock()
+		if !d.alert.Escalated {
+			d.tr.notified = d.alert.State
+		} else if d.tr.since.Equal(d.since) {
+			d.tr.escalated = true
+		}
+		m.mu.Unlock()
+	}
+	return firstErr
+}
+
+func (m *Monitor) track(id string, kind Kind) *tracked {
+	key := alarmKey{deviceID: id, kind: kind}
+	tr, ok := m.alarms[key]
+	if !ok {
+		tr = &tracked{state: device.AlarmStateOK, notified: device.AlarmStateOK}
+		m.alarms[key] = tr
+	}
+	return tr
+}
+
+func send(ctx context.Context, notifiers []notify.Notifier, a Alert) error {
+	var firstErr error
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, a.Message()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,211 @@
+package alarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/config"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	alerts []string
+	err    error
+}
+
+func (r *recorder) Notify(ctx context.Context, m notify.Message) error {
+	s := m.Title
+	if m.Fields["escalated"] == "true" {
+		s += " (escalated)"
+	}
+	r.alerts = append(r.alerts, s)
+	return r.err
+}
+
+func protect(smoke, co device.AlarmState, testing bool) *device.SmokeAlarm {
+	return &device.SmokeAlarm{
+		DeviceID:           "123",
+		NameLong:           "Hallway Nest Protect",
+		SmokeAlarmState:    smoke,
+		CoAlarmState:       co,
+		IsManualTestActive: testing,
+	}
+}
+
+func Test_MonitorDebounceAndEscalation(t *testing.T) {
+	ctx := context.Background()
+	notified, escalation := &recorder{}, &recorder{}
+	m := NewMonitor(Policy{Debounce: time.Minute, EscalateAfter: 10 * time.Minute, Escalation: []notify.Notifier{escalation}}, notified)
+
+	start := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	ok, warning := device.AlarmStateOK, device.AlarmStateWarning
+
+	// a short warning is debounced
+	assert.NoError(t, m.Observe(ctx, protect(ok, ok, false), start))
+	assert.NoError(t, m.Observe(ctx, protect(ok, warning, false), start))
+	assert.NoError(t, m.Observe(ctx, protect(ok, ok, false), start.Add(30*time.Second)))
+	assert.Empty(t, notified.alerts)
+
+	// a persistent warning is notified, then escalated
+	assert.NoError(t, m.Observe(ctx, protect(ok, warning, false), start.Add(time.Hour)))
+	assert.NoError(t, m.Tick(ctx, start.Add(time.Hour+time.Minute)))
+	assert.NoError(t, m.Tick(ctx, start.Add(time.Hour+10*time.Minute)))
+	assert.NoError(t, m.Tick(ctx, start.Add(time.Hour+20*time.Minute)))
+	assert.NoError(t, m.Observe(ctx, protect(ok, ok, false), start.Add(2*time.Hour)))
+
+	assert.Equal(t, []string{
+		"co alarm warning: Hallway Nest Protect",
+		"co alarm cleared: Hallway Nest Protect",
+	}, notified.alerts)
+	assert.Equal(t, []string{"co alarm warning: Hallway Nest Protect (escalated)"}, escalation.alerts)
+}
+
+func Test_MonitorEmergency(t *testing.T) {
+	ctx := context.Background()
+	notified, escalation := &recorder{}, &recorder{}
+	m := NewMonitor(Policy{Debounce: time.Hour, Escalation: []notify.Notifier{escalation}}, notified)
+
+	at := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	assert.NoError(t, m.Observe(ctx, protect(device.AlarmStateEmergency, device.AlarmStateOK, false), at))
+	assert.NoError(t, m.Observe(ctx, protect(device.AlarmStateEmergency, device.AlarmStateOK, false), at.Add(time.Second)))
+
+	assert.Equal(t, []string{"smoke alarm emergency: Hallway Nest Protect"}, notified.alerts, "emergencies should not be debounced")
+	assert.Equal(t, []string{"smoke alarm emergency: Hallway Nest Protect (escalated)"}, escalation.alerts)
+}
+
+func Test_MonitorManualTest(t *testing.T) {
+	ctx := context.Background()
+	notified := &recorder{}
+	m := NewMonitor(Policy{}, notified)
+
+	at := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	assert.NoError(t, m.Observe(ctx, protect(device.AlarmStateEmergency, device.AlarmStateWarning, true), at))
+	assert.NoError(t, m.Tick(ctx, at.Add(time.Minute)))
+	assert.NoError(t, m.Observe(ctx, protect(device.AlarmStateOK, device.AlarmStateOK, false), at.Add(2*time.Minute)))
+	assert.Empty(t, notified.alerts, "alarms during a manual test should be suppressed")
+
+	notified.err = errors.New("unreachable")
+	err := m.Observe(ctx, protect(device.AlarmStateWarning, device.AlarmStateOK, false), at.Add(3*time.Minute))
+	assert.EqualError(t, err, "unreachable")
+	assert.Equal(t, []string{"smoke alarm warning: Hallway Nest Protect"}, notified.alerts)
+
+	notified.err = nil
+	assert.NoError(t, m.Tick(ctx, at.Add(4*time.Minute)))
+	assert.NoError(t, m.Tick(ctx, at.Add(5*time.Minute)))
+	assert.Equal(t, []string{
+		"smoke alarm warning: Hallway Nest Protect",
+		"smoke alarm warning: Hallway Nest Protect",
+	}, notified.alerts, "an alert that failed to send should be retried once")
+}
+
+func Test_MonitorEmergencyRetry(t *testing.T) {
+	ctx := context.Background()
+	notified, escalation := &recorder{err: errors.New("unreachable")}, &recorder{}
+	m := NewMonitor(Policy{Escalation: []notify.Notifier{escalation}}, notified)
+
+	at := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	assert.EqualError(t, m.Observe(ctx, protect(device.AlarmStateOK, device.AlarmStateEmergency, false), at), "unreachable")
+	notified.err = nil
+	assert.NoError(t, m.Tick(ctx, at.Add(time.Second)))
+	assert.NoError(t, m.Tick(ctx, at.Add(2*time.Second)))
+
+	assert.Equal(t, []string{
+		"co alarm emergency: Hallway Nest Protect",
+		"co alarm emergency: Hallway Nest Protect",
+	}, notified.alerts, "an emergency that failed to send should be retried")
+	assert.Equal(t, []string{"co alarm emergency: Hallway Nest Protect (escalated)"}, escalation.alerts)
+}
+
+func Test_MonitorSlowNotifier(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	slow := notify.NotifierFunc(func(ctx context.Context, msg notify.Message) error {
+		<-release
+		return nil
+	})
+	m := NewMonitor(Policy{}, slow)
+
+	at := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	done := make(chan error)
+	go func() {
+		done <- m.Observe(ctx, protect(device.AlarmStateEmergency, device.AlarmStateOK, false), at)
+	}()
+
+	// observing another alarm should not wait for the slow notifier
+	other := protect(device.AlarmStateOK, device.AlarmStateOK, false)
+	other.DeviceID = "456"
+	observed := make(chan error)
+	go func() {
+		observed <- m.Observe(ctx, other, at)
+	}()
+	select {
+	case err := <-observed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Observe blocked on a notifier")
+	}
+	close(release)
+	assert.NoError(t, <-done)
+}
+
+func Test_MonitorRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, state := range []string{"ok", "emergency", "emergency"} {
+			fmt.Fprintf(w, "event: put\ndata: {\"path\":\"/devices/smoke_co_alarms/123\",\"data\":{\"co_alarm_state\":\"%s\"}}\n", state)
+		}
+	}))
+	defer ts.Close()
+
+	s, _ := nest.NewStream(&config.Config{APIURL: ts.URL}, ts.Client())
+	events, err := s.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var alerts []notify.Message
+	m := NewMonitor(Policy{}, notify.NotifierFunc(func(ctx context.Context, m notify.Message) error {
+		alerts = append(alerts, m)
+		return nil
+	}))
+	assert.NoError(t, m.Run(context.Background(), events, time.Hour))
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "123", alerts[0].DeviceID)
+		assert.Equal(t, notify.Critical, alerts[0].Severity)
+		assert.Equal(t, map[string]string{"kind": "co", "state": "emergency", "previous": "ok"}, alerts[0].Fields)
+	}
+}
+
+func Test_AlertMessage(t *testing.T) {
+	at := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		alert Alert
+		want  notify.Message
+	}{
+		{
+			"emergency",
+			Alert{DeviceID: "123", Name: "Hallway", Kind: CO, State: device.AlarmStateEmergency, Previous: device.AlarmStateOK, Escalated: true, Time: at},
+			notify.Message{Title: "co alarm emergency: Hallway", Body: "co alarm emergency: Hallway", Severity: notify.Critical, DeviceID: "123", Time: at,
+				Fields: map[string]string{"kind": "co", "state": "emergency", "previous": "ok", "escalated": "true"}},
+		},
+		{
+			"cleared",
+			Alert{DeviceID: "123", Kind: Smoke, State: device.AlarmStateOK, Previous: device.AlarmStateWarning, Time: at},
+			notify.Message{Title: "smoke alarm cleared: 123", Body: "smoke alarm cleared: 123", Severity: notify.Info, DeviceID: "123", Time: at,
+				Fields: map[string]string{"kind": "smoke", "state": "ok", "previous": "warning"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.alert.Message())
+		})
+	}
+}
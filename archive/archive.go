@@ -0,0 +1,180 @@
+package archive
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/internal/fileutil"
+)
+
+// Media kinds stored with a record
+const (
+	MediaImage     = "image"
+	MediaAnimation = "animation"
+)
+
+// Record is the metadata of an archived camera event.
+type Record struct {
+	DeviceID        string    `json:"device_id"`
+	CameraName      string    `json:"camera_name,omitempty"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time,omitempty"`
+	HasSound        bool      `json:"has_sound"`
+	HasMotion       bool      `json:"has_motion"`
+	HasPerson       bool      `json:"has_person"`
+	ActivityZoneIDs []string  `json:"activity_zone_ids,omitempty"`
+	// Media lists the names of the files stored with the record.
+	Media []string `json:"media,omitempty"`
+}
+
+// Media is an image downloaded for a record.
+type Media struct {
+	Kind        string
+	ContentType string
+	Body        io.ReadCloser
+}
+
+// Archive stores camera event records and their media. Store takes ownership
+// of the media bodies and closes them.
+type Archive interface {
+	Store(rec Record, media ...Media) error
+}
+
+// FileArchive stores each record in its own directory below a root directory.
+// Device ids are path escaped:
+//
+//	<root>/<device id>/<start time>/event.json
+//	<root>/<device id>/<start time>/image-<start time>.jpg
+//	<root>/<device id>/<start time>/animation-<start time>.gif
+//
+type FileArchive struct {
+	root string
+}
+
+// NewFileArchive creates an archive rooted at dir. Directories are created as needed.
+func NewFileArchive(dir string) *FileArchive {
+	return &FileArchive{root: dir}
+}
+
+// Store writes the media and record to disk. Storing a record again replaces
+// the media given and keeps media of other kinds stored before, so a record
+// stored again after a failed download keeps what was already archived.
+func (f *FileArchive) Store(rec Record, media ...Media) error {
+	defer func() {
+		for _, m := range media {
+			m.Body.Close()
+		}
+	}()
+
+	dir := f.Dir(rec)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Media is downloaded to a temporary directory and moved into place once
+	// every download has succeeded.
+	tmp, err := ioutil.TempDir(dir, ".store-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	names := map[string]string{}
+	for _, m := range media {
+		path, err := nest.SaveMediaToDir(tmp, m.Kind, rec.StartTime, m.ContentType, m.Body)
+		if err != nil {
+			return err
+		}
+		names[m.Kind] = filepath.Base(path)
+	}
+
+	prev, err := readRecord(filepath.Join(dir, "event.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	rec.Media = nil
+	for _, name := range prev.Media {
+		replaced, ok := names[mediaKind(name)]
+		if !ok {
+			rec.Media = append(rec.Media, name)
+		} else if replaced != name {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+	for _, m := range media {
+		name := names[m.Kind]
+		if err := os.Rename(filepath.Join(tmp, name), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		rec.Media = append(rec.Media, name)
+	}
+
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(filepath.Join(dir, "event.json"), b, 0644)
+}
+
+// Dir returns the directory a record is stored in.
+func (f *FileArchive) Dir(rec Record) string {
+	return filepath.Join(f.deviceDir(rec.DeviceID), rec.StartTime.UTC().Format("20060102T150405Z"))
+}
+
+// deviceDir returns the directory the records of a device are stored in. The
+// device id is escaped so it is a single path element below the root.
+func (f *FileArchive) deviceDir(deviceID string) string {
+	name := url.PathEscape(deviceID)
+	if name != "" && strings.Trim(name, ".") == "" {
+		name = strings.Repeat("%2E", len(name))
+	}
+	return filepath.Join(f.root, name)
+}
+
+// mediaKind returns the kind of a stored media file from its name.
+func mediaKind(name string) string {
+	if i := strings.Index(name, "-"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func readRecord(path string) (Record, error) {
+	var rec Record
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	err = json.Unmarshal(b, &rec)
+	return rec, err
+}
+
+// Records returns the records stored for a device, oldest first.
+func (f *FileArchive) Records(deviceID string) ([]Record, error) {
+	root := f.deviceDir(deviceID)
+	dirs, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, d := range dirs {
+		rec, err := readRecord(filepath.Join(root, d.Name(), "event.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := NewFileArchive(dir)
+	rec := Record{
+		DeviceID:        "123",
+		StartTime:       time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC),
+		EndTime:         time.Date(2020, 3, 24, 11, 2, 0, 0, time.UTC),
+		HasPerson:       true,
+		ActivityZoneIDs: []string{"1"},
+	}
+	media := func() []Media {
+		return []Media{
+			{Kind: MediaImage, ContentType: "image/jpeg", Body: ioutil.NopCloser(strings.NewReader("jpeg"))},
+			{Kind: MediaAnimation, ContentType: "image/gif", Body: ioutil.NopCloser(strings.NewReader("gif"))},
+		}
+	}
+	assert.NoError(t, a.Store(rec, media()...))
+	assert.NoError(t, a.Store(rec, media()...), "storing a record again should replace it")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "123", "20200324T110000Z", "image-20200324T110000Z.jpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", string(b))
+
+	records, err := a.Records("123")
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.True(t, records[0].HasPerson)
+		assert.Equal(t, []string{"1"}, records[0].ActivityZoneIDs)
+		assert.Equal(t, []string{"image-20200324T110000Z.jpg", "animation-20200324T110000Z.gif"}, records[0].Media)
+	}
+
+	records, err = a.Records("456")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	// A download that failed when storing again keeps the media archived before.
+	assert.NoError(t, a.Store(rec, media()[1]))
+	records, err = a.Records("123")
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, []string{"image-20200324T110000Z.jpg", "animation-20200324T110000Z.gif"}, records[0].Media)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "123", "20200324T110000Z", "image-20200324T110000Z.jpg"))
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", string(b))
+}
+
+func Test_FileArchiveDeviceID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "root")
+	a := NewFileArchive(root)
+	for _, id := range []string{"..", "../escaped", "enterprises/p/devices/1"} {
+		rec := Record{DeviceID: id, StartTime: time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)}
+		assert.NoError(t, a.Store(rec), id)
+
+		rel, err := filepath.Rel(root, a.Dir(rec))
+		assert.NoError(t, err)
+		assert.Len(t, strings.Split(rel, string(filepath.Separator)), 2, "%s should be stored in its own directory below the root", id)
+
+		records, err := a.Records(id)
+		assert.NoError(t, err)
+		assert.Len(t, records, 1, id)
+	}
+}
@@ -0,0 +1,96 @@
+// Package archive records camera events beyond Nest's video history retention.
+// A Recorder watches camera streams and, for every camera event, downloads the
+// event image and animation and stores them with the event metadata in an Archive.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/backend"
+)
+
+// Recorder archives camera events as they end, once the event image and
+// animation are complete.
+type Recorder struct {
+	cameras backend.Cameras
+	archive Archive
+}
+
+// NewRecorder creates a recorder that downloads media with cameras and stores
+// records in archive.
+func NewRecorder(cameras backend.Cameras, archive Archive) *Recorder {
+	return &Recorder{cameras: cameras, archive: archive}
+}
+
+// Run archives the camera events read from a Stream until events is closed or
+// ctx is done. Errors archiving an event are logged and do not stop the
+// recorder. To record several cameras, call Run with each camera's stream.
+// When ctx is done, events are discarded until the stream is closed.
+func (r *Recorder) Run(ctx context.Context, events <-chan nest.Event) error {
+	notifications := nest.WatchCameraEvents(ctx, events)
+	for {
+		select {
+		case <-ctx.Done():
+			go func() {
+				for range events {
+				}
+			}()
+			return ctx.Err()
+		case n, ok := <-notifications:
+			if !ok {
+				return nil
+			}
+			if n.Kind != nest.CameraEventEnded {
+				continue
+			}
+			if err := r.Record(ctx, n); err != nil {
+				log.Printf("error archiving camera event: %v\n", err)
+			}
+		}
+	}
+}
+
+// Record downloads the media of a camera event and stores it in the archive.
+// The record is stored even if a download fails; the first download error is
+// returned after storing.
+func (r *Recorder) Record(ctx context.Context, n nest.CameraNotification) error {
+	rec := Record{
+		DeviceID:        n.DeviceID,
+		StartTime:       n.Event.StartTime,
+		EndTime:         n.Event.EndTime,
+		HasSound:        n.Event.HasSound,
+		HasMotion:       n.Event.HasMotion,
+		HasPerson:       n.Event.HasPerson,
+		ActivityZoneIDs: n.Event.ActivityZoneIDs,
+	}
+	if n.Camera != nil {
+		rec.CameraName = n.Camera.Name
+	}
+
+	var media []Media
+	var fetchErr error
+	for _, m := range []struct{ kind, url string }{
+		{MediaImage, n.Event.ImageURL},
+		{MediaAnimation, n.Event.AnimatedImageURL},
+	} {
+		if m.url == "" {
+			continue
+		}
+		body, contentType, err := r.cameras.FetchMedia(ctx, m.url)
+		if err != nil {
+			if fetchErr == nil {
+				fetchErr = fmt.Errorf("camera %s %s: %v", n.DeviceID, m.kind, err)
+			}
+			continue
+		}
+		media = append(media, Media{Kind: m.kind, ContentType: contentType, Body: body})
+	}
+
+	if err := r.archive.Store(rec, media...); err != nil {
+		return err
+	}
+	return fetchErr
+}
@@ -0,0 +1,108 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/backend"
+	"github.com/jtsiros/nest/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCameras struct {
+	backend.Cameras
+}
+
+func (fakeCameras) FetchMedia(ctx context.Context, mediaURL string) (io.ReadCloser, string, error) {
+	if strings.HasSuffix(mediaURL, "/expired") {
+		return nil, "", errors.New("expected status code 200, got 404")
+	}
+	return ioutil.NopCloser(strings.NewReader(mediaURL)), "image/gif", nil
+}
+
+type memoryArchive struct {
+	mu      sync.Mutex
+	records []Record
+	media   map[string]string
+}
+
+func (m *memoryArchive) Store(rec Record, media ...Media) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, md := range media {
+		b, _ := ioutil.ReadAll(md.Body)
+		md.Body.Close()
+		m.media[md.Kind] = string(b)
+	}
+	m.records = append(m.records, rec)
+	return nil
+}
+
+func Test_RecorderRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, e := range []struct{ start, end string }{
+			{"2020-03-24T10:00:00Z", "2020-03-24T10:01:00Z"},
+			{"2020-03-24T11:00:00Z", "2020-03-24T10:01:00Z"},
+			{"2020-03-24T11:00:00Z", "2020-03-24T11:02:00Z"},
+			{"2020-03-24T11:00:00Z", "2020-03-24T11:02:00Z"},
+		} {
+			fmt.Fprintf(w, "event: put\ndata: {\"path\":\"/devices/cameras/123\",\"data\":{\"name\":\"Den\",\"last_event\":{\"has_person\":true,\"start_time\":\"%s\",\"end_time\":\"%s\",\"image_url\":\"https://nest/image\",\"animated_image_url\":\"https://nest/animation\"}}}\n", e.start, e.end)
+		}
+	}))
+	defer ts.Close()
+
+	s, _ := nest.NewStream(&config.Config{APIURL: ts.URL}, ts.Client())
+	events, err := s.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &memoryArchive{media: map[string]string{}}
+	assert.NoError(t, NewRecorder(fakeCameras{}, a).Run(context.Background(), events))
+	if assert.Len(t, a.records, 1, "only the event that ended while watching should be archived") {
+		assert.Equal(t, "123", a.records[0].DeviceID)
+		assert.Equal(t, "Den", a.records[0].CameraName)
+		assert.True(t, a.records[0].HasPerson)
+	}
+	assert.Equal(t, map[string]string{MediaImage: "https://nest/image", MediaAnimation: "https://nest/animation"}, a.media)
+}
+
+func Test_RecorderRunCanceled(t *testing.T) {
+	events := make(chan nest.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, NewRecorder(fakeCameras{}, &memoryArchive{}).Run(ctx, events))
+
+	// Camera events sent after Run returns should not block the stream.
+	for _, start := range []string{"2020-03-24T10:00:00Z", "2020-03-24T11:00:00Z", "2020-03-24T12:00:00Z"} {
+		data := fmt.Sprintf(`{"path":"/devices/cameras/123","data":{"last_event":{"start_time":%q}}}`, start)
+		select {
+		case events <- nest.NewEvent("put", []byte(data)):
+		case <-time.After(time.Second):
+			t.Fatal("sending an event blocked after Run returned")
+		}
+	}
+	close(events)
+}
+
+func Test_RecordFetchError(t *testing.T) {
+	a := &memoryArchive{media: map[string]string{}}
+	n := nest.CameraNotification{DeviceID: "123", Kind: nest.CameraEventEnded}
+	n.Event.ImageURL = "https://nest/expired"
+	n.Event.AnimatedImageURL = "https://nest/animation"
+
+	err := NewRecorder(fakeCameras{}, a).Record(context.Background(), n)
+	assert.EqualError(t, err, "camera 123 image: expected status code 200, got 404")
+	assert.Len(t, a.records, 1, "metadata should be stored even if a download fails")
+	assert.Equal(t, map[string]string{MediaAnimation: "https://nest/animation"}, a.media)
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/jtsiros/nest/config"
+	"golang.org/x/oauth2"
+)
+
+// Flow runs the OAuth2 authorization code flow. It starts a loopback HTTP
+// server to receive the authorization code, asks the user to visit the
+// authorization URL and exchanges the code for a token.
+type Flow struct {
+	Config *oauth2.Config
+
+	// Addr is the address the callback server listens on. When empty, the host
+	// and port of Config.RedirectURL are used, or a random port on 127.0.0.1.
+	Addr string
+
+	// OpenURL is called with the authorization URL, e.g. to open a browser.
+	// When nil, the URL is printed to standard error.
+	OpenURL func(authURL string) error
+}
+
+// NewFlow creates an authorization code flow for the Nest API.
+func NewFlow(cfg config.Config) *Flow {
+	return &Flow{Config: NewConfig(cfg)}
+}
+
+// Token runs the flow and returns the token once the user has authorized the
+// application. It blocks until the callback is received or ctx is done.
+// Callbacks that do not carry the state sent with the authorization request,
+// which may be forged, are rejected and the flow keeps waiting.
+func (f *Flow) Token(ctx context.Context) (*oauth2.Token, error) {
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+
+	conf := *f.Config
+	addr, path, err := f.callback()
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if conf.RedirectURL == "" {
+		conf.RedirectURL = "http://" + ln.Addr().String() + path
+	}
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			http.Error(w, "Invalid state.", http.StatusBadRequest)
+		case q.Get("error") != "":
+			http.Error(w, "Authorization failed.", http.StatusBadRequest)
+			sendErr(errs, fmt.Errorf("auth: authorization failed: %s", q.Get("error")))
+		case q.Get("code") == "":
+			http.Error(w, "Missing authorization code.", http.StatusBadRequest)
+			sendErr(errs, errors.New("auth: missing authorization code"))
+		default:
+			fmt.Fprint(w, "Authorization complete. You can close this window.")
+			select {
+			case codes <- q.Get("code"):
+			default:
+			}
+		}
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	if err := f.openURL(conf.AuthCodeURL(state)); err != nil {
+		return nil, err
+	}
+
+	var code string
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-errs:
+		return nil, err
+	case code = <-codes:
+	}
+	return conf.Exchange(ctx, code)
+}
+
+// callback returns the listen address and path of the callback server.
+func (f *Flow) callback() (string, string, error) {
+	addr, path := f.Addr, "/callback"
+	if f.Config.RedirectURL != "" {
+		u, err := url.Parse(f.Config.RedirectURL)
+		if err != nil {
+			return "", "", err
+		}
+		if addr == "" {
+			addr = u.Host
+		}
+		if u.Path != "" {
+			path = u.Path
+		}
+	}
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	return addr, path, nil
+}
+
+func (f *Flow) openURL(authURL string) error {
+	if f.OpenURL != nil {
+		return f.OpenURL(authURL)
+	}
+	_, err := fmt.Fprintf(os.Stderr, "Visit this URL to authorize access: %s\n", authURL)
+	return err
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sendErr(errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	default:
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTokenServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal(t, "authorization_code", r.Form.Get("grant_type"))
+		assert.Equal(t, "abc", r.Form.Get("code"))
+		assert.Equal(t, "123", r.Form.Get("client_id"))
+		assert.Equal(t, "s3cret", r.Form.Get("client_secret"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"c.token","expires_in":315360000}`)
+	}))
+}
+
+// authorize returns an OpenURL func that follows the authorization URL back to
+// the callback server with the given query.
+func authorize(t *testing.T, query func(state string) url.Values) func(string) error {
+	return func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "123", u.Query().Get("client_id"))
+		callback := u.Query().Get("redirect_uri") + "?" + query(u.Query().Get("state")).Encode()
+		go func() {
+			resp, err := http.Get(callback)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+}
+
+func Test_FlowToken(t *testing.T) {
+	ts := newTokenServer(t)
+	defer ts.Close()
+
+	f := NewFlow(config.Config{ClientID: "123", Secret: "s3cret", TokenURL: ts.URL})
+	f.OpenURL = authorize(t, func(state string) url.Values {
+		return url.Values{"state": {state}, "code": {"abc"}}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tok, err := f.Token(ctx)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "c.token", tok.AccessToken)
+	}
+}
+
+func Test_FlowCallbackErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func(state string) url.Values
+		err   string
+	}{
+		{"denied", func(state string) url.Values { return url.Values{"state": {state}, "error": {"access_denied"}} }, "auth: authorization failed: access_denied"},
+		{"missing code", func(state string) url.Values { return url.Values{"state": {state}} }, "auth: missing authorization code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFlow(config.Config{ClientID: "123"})
+			f.OpenURL = authorize(t, tt.query)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := f.Token(ctx)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func Test_FlowStateMismatch(t *testing.T) {
+	ts := newTokenServer(t)
+	defer ts.Close()
+
+	f := NewFlow(config.Config{ClientID: "123", Secret: "s3cret", TokenURL: ts.URL})
+	f.OpenURL = func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+		callback := u.Query().Get("redirect_uri") + "?"
+		go func() {
+			for _, state := range []string{"", "forged"} {
+				resp, err := http.Get(callback + url.Values{"state": {state}, "code": {"forged"}}.Encode())
+				if assert.NoError(t, err) {
+					resp.Body.Close()
+					assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				}
+			}
+			resp, err := http.Get(callback + url.Values{"state": {u.Query().Get("state")}, "code": {"abc"}}.Encode())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tok, err := f.Token(ctx)
+	if assert.NoError(t, err, "callbacks with the wrong state should not end the flow") {
+		assert.Equal(t, "c.token", tok.AccessToken)
+	}
+}
+
+func Test_FlowRedirectURL(t *testing.T) {
+	f := NewFlow(config.Config{ClientID: "123"})
+	f.Config.RedirectURL = "http://localhost:8085/oauth/nest"
+	addr, path, err := f.callback()
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:8085", addr)
+	assert.Equal(t, "/oauth/nest", path)
+
+	f.Config.RedirectURL = ""
+	addr, path, err = f.callback()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:0", addr)
+	assert.Equal(t, "/callback", path)
+}
@@ -7,13 +7,17 @@ import (
 
 // NewConfig creates a new oauth2-backed configuration for handling Nest API
 // Authentication. This is intended to be used with an oauth2.Client object.
+// Endpoints not set in cfg default to the Nest endpoints.
 func NewConfig(cfg config.Config) *oauth2.Config {
+	cfg = cfg.WithDefaults()
 	conf := &oauth2.Config{
-		ClientID: cfg.ClientID,
-		Scopes:   []string{"authorization_code"},
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.Secret,
+		Scopes:       cfg.Scopes,
 		Endpoint: oauth2.Endpoint{
-			TokenURL: config.TokenURL,
-			AuthURL:  config.AuthURL,
+			TokenURL:  cfg.TokenURL,
+			AuthURL:   cfg.AuthURL,
+			AuthStyle: oauth2.AuthStyleInParams,
 		},
 	}
 	return conf
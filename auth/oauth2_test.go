@@ -8,12 +8,26 @@ import (
 )
 
 func TestValidOAuthConfig(t *testing.T) {
-	appCfg := config.Config{ClientID: "123"}
+	appCfg := config.Config{ClientID: "123", Secret: "s3cret"}
 	cfg := NewConfig(appCfg)
 	assert.Equal(t, "123", cfg.ClientID)
-	assert.Equal(t, []string{"authorization_code"}, cfg.Scopes)
-	assert.NotNil(t, cfg.Endpoint.AuthURL)
-	assert.NotNil(t, cfg.Endpoint.TokenURL)
+	assert.Equal(t, "s3cret", cfg.ClientSecret)
+	assert.Empty(t, cfg.Scopes)
+	assert.Equal(t, config.AuthURL, cfg.Endpoint.AuthURL)
+	assert.Equal(t, config.TokenURL, cfg.Endpoint.TokenURL)
+}
+
+func TestCustomOAuthConfig(t *testing.T) {
+	appCfg := config.Config{
+		ClientID: "123",
+		TokenURL: "http://127.0.0.1:8080/token",
+		AuthURL:  "http://127.0.0.1:8080/auth",
+		Scopes:   []string{"read"},
+	}
+	cfg := NewConfig(appCfg)
+	assert.Equal(t, "http://127.0.0.1:8080/token", cfg.Endpoint.TokenURL)
+	assert.Equal(t, "http://127.0.0.1:8080/auth", cfg.Endpoint.AuthURL)
+	assert.Equal(t, []string{"read"}, cfg.Scopes)
 }
 
 func TestStaticToken(t *testing.T) {
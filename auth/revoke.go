@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jtsiros/nest/config"
+	"golang.org/x/oauth2"
+)
+
+// Revoke revokes an access token, deauthorizing the application for the user
+// who granted it. The HTTP client is taken from ctx as with oauth2, and
+// defaults to http.DefaultClient.
+// See https://developers.nest.com/guides/api/how-to-auth#deauthorization
+//
+func Revoke(ctx context.Context, token string) error {
+	return RevokeAt(ctx, config.RevokeURL, token)
+}
+
+// RevokeAt revokes an access token at the given revocation endpoint, e.g.
+// config.Config.RevokeURL. See Revoke.
+func RevokeAt(ctx context.Context, revokeURL, token string) error {
+	if token == "" {
+		return fmt.Errorf("auth: no token to revoke")
+	}
+	u := strings.TrimSuffix(revokeURL, "/") + "/" + url.PathEscape(token)
+	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+
+	client := http.DefaultClient
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && c != nil {
+		client = c
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("auth: revoking token failed with status code %d", resp.StatusCode)
+	}
+	return nil
+}
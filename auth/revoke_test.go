@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func Test_RevokeAt(t *testing.T) {
+	var method, path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		if r.URL.Path == "/oauth2/access_tokens/c.unknown" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, ts.Client())
+
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"revoked", "c.token", ""},
+		{"unknown token", "c.unknown", "auth: revoking token failed with status code 404"},
+		{"empty token", "", "auth: no token to revoke"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RevokeAt(ctx, ts.URL+"/oauth2/access_tokens", tt.token)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.MethodDelete, method)
+			assert.Equal(t, "/oauth2/access_tokens/"+tt.token, path)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jtsiros/nest/internal/fileutil"
+	"golang.org/x/oauth2"
+)
+
+// ErrNoToken is returned by a TokenStore that has no token.
+var ErrNoToken = errors.New("auth: no token stored")
+
+// TokenStore loads and saves OAuth2 tokens so an application does not need to
+// be authorized again every time it starts.
+type TokenStore interface {
+	Load() (*oauth2.Token, error)
+	Save(*oauth2.Token) error
+}
+
+// FileTokenStore persists a token as JSON to a file readable only by its owner.
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTokenStore creates a token store backed by the file at path. The file
+// is created on the first Save.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load reads the token from the file. ErrNoToken is returned when the file does
+// not exist.
+func (f *FileTokenStore) Load() (*oauth2.Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal(b, &tok); err != nil {
+		return nil, err
+	}
+	return &tok, nil
+}
+
+// Save atomically replaces the file with the token, with mode 0600.
+func (f *FileTokenStore) Save(tok *oauth2.Token) error {
+	b, err := json.MarshalIndent(tok, "", "  ")
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return fileutil.WriteFile(f.path, b, 0600)
+}
+
+// EnvTokenStore reads a token from an environment variable. The variable holds
+// either a JSON encoded token or a bare access token, as issued by the Nest
+// developer console.
+type EnvTokenStore struct {
+	Name string
+}
+
+// NewEnvTokenStore creates a token store backed by the named environment variable.
+func NewEnvTokenStore(name string) *EnvTokenStore {
+	return &EnvTokenStore{Name: name}
+}
+
+// Load reads the token from the environment. ErrNoToken is returned when the
+// variable is not set.
+func (e *EnvTokenStore) Load() (*oauth2.Token, error) {
+	v := strings.TrimSpace(os.Getenv(e.Name))
+	if v == "" {
+		return nil, ErrNoToken
+	}
+	if !strings.HasPrefix(v, "{") {
+		return &oauth2.Token{AccessToken: v}, nil
+	}
+	var tok oauth2.Token
+	if err := json.Unmarshal([]byte(v), &tok); err != nil {
+		return nil, err
+	}
+	return &tok, nil
+}
+
+// Save sets the environment variable of the current process to the JSON
+// encoded token, so it is inherited by child processes.
+func (e *EnvTokenStore) Save(tok *oauth2.Token) error {
+	b, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	return os.Setenv(e.Name, string(b))
+}
+
+// NewStoreTokenSource returns a TokenSource that loads its token from store on
+// first use and caches it. When conf is not nil, expired tokens are refreshed
+// with conf and every new token is saved to store.
+func NewStoreTokenSource(ctx context.Context, conf *oauth2.Config, store TokenStore) oauth2.TokenSource {
+	return &storeTokenSource{ctx: ctx, conf: conf, store: store}
+}
+
+type storeTokenSource struct {
+	ctx   context.Context
+	conf  *oauth2.Config
+	store TokenStore
+
+	mu  sync.Mutex
+	tok *oauth2.Token
+	src oauth2.TokenSource
+}
+
+func (s *storeTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.src == nil {
+		tok, err := s.store.Load()
+		if err != nil {
+			return nil, err
+		}
+		s.tok = tok
+		if s.conf != nil {
+			s.src = s.conf.TokenSource(s.ctx, tok)
+		} else {
+			s.src = oauth2.StaticTokenSource(tok)
+		}
+	}
+
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok.AccessToken != s.tok.AccessToken || tok.RefreshToken != s.tok.RefreshToken || !tok.Expiry.Equal(s.tok.Expiry) {
+		if err := s.store.Save(tok); err != nil {
+			return nil, err
+		}
+		s.tok = tok
+	}
+	return tok, nil
+}
+
+// StoredToken returns the token in store, running the flow and saving the new
+// token when the store has none.
+func (f *Flow) StoredToken(ctx context.Context, store TokenStore) (*oauth2.Token, error) {
+	tok, err := store.Load()
+	if err != ErrNoToken {
+		return tok, err
+	}
+	tok, err = f.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Save(tok); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+type memoryTokenStore struct {
+	tok   *oauth2.Token
+	loads int
+	saves int
+}
+
+func (m *memoryTokenStore) Load() (*oauth2.Token, error) {
+	m.loads++
+	if m.tok == nil {
+		return nil, ErrNoToken
+	}
+	return m.tok, nil
+}
+
+func (m *memoryTokenStore) Save(tok *oauth2.Token) error {
+	m.saves++
+	m.tok = tok
+	return nil
+}
+
+func Test_FileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "token.json")
+	store := NewFileTokenStore(path)
+
+	_, err = store.Load()
+	assert.Equal(t, ErrNoToken, err)
+
+	tok := &oauth2.Token{AccessToken: "c.token", TokenType: "Bearer", Expiry: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	assert.NoError(t, store.Save(tok))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, tok.AccessToken, loaded.AccessToken)
+	assert.True(t, tok.Expiry.Equal(loaded.Expiry))
+}
+
+func Test_EnvTokenStore(t *testing.T) {
+	const name = "NEST_TEST_TOKEN"
+	defer os.Unsetenv(name)
+	store := NewEnvTokenStore(name)
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   error
+	}{
+		{"unset", "", "", ErrNoToken},
+		{"bare token", "c.token\n", "c.token", nil},
+		{"json token", `{"access_token":"c.json","token_type":"Bearer"}`, "c.json", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(name, tt.value)
+			tok, err := store.Load()
+			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				assert.Equal(t, tt.want, tok.AccessToken)
+			}
+		})
+	}
+
+	assert.NoError(t, store.Save(&oauth2.Token{AccessToken: "c.saved"}))
+	tok, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "c.saved", tok.AccessToken)
+}
+
+func Test_StoreTokenSource(t *testing.T) {
+	store := &memoryTokenStore{tok: &oauth2.Token{AccessToken: "c.token"}}
+	src := NewStoreTokenSource(context.Background(), nil, store)
+	for i := 0; i < 3; i++ {
+		tok, err := src.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "c.token", tok.AccessToken)
+	}
+	assert.Equal(t, 1, store.loads, "token should be cached")
+	assert.Equal(t, 0, store.saves)
+
+	_, err := NewStoreTokenSource(context.Background(), nil, &memoryTokenStore{}).Token()
+	assert.Equal(t, ErrNoToken, err)
+}
+
+func Test_StoreTokenSourceRefresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		assert.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+		assert.Equal(t, "r.token", r.Form.Get("refresh_token"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"c.new","refresh_token":"r.token","expires_in":3600}`)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig(config.Config{ClientID: "123", Secret: "s3cret", TokenURL: ts.URL})
+	store := &memoryTokenStore{tok: &oauth2.Token{AccessToken: "c.old", RefreshToken: "r.token", Expiry: time.Now().Add(-time.Hour)}}
+
+	tok, err := NewStoreTokenSource(context.Background(), conf, store).Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "c.new", tok.AccessToken)
+	assert.Equal(t, 1, store.saves)
+	assert.Equal(t, "c.new", store.tok.AccessToken)
+}
+
+func Test_FlowStoredToken(t *testing.T) {
+	ts := newTokenServer(t)
+	defer ts.Close()
+
+	f := NewFlow(config.Config{ClientID: "123", Secret: "s3cret", TokenURL: ts.URL})
+	opened := 0
+	open := authorize(t, func(state string) url.Values {
+		return url.Values{"state": {state}, "code": {"abc"}}
+	})
+	f.OpenURL = func(authURL string) error {
+		opened++
+		return open(authURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store := &memoryTokenStore{}
+	for i := 0; i < 2; i++ {
+		tok, err := f.StoredToken(ctx, store)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "c.token", tok.AccessToken)
+		}
+	}
+	assert.Equal(t, 1, opened, "stored token should be reused")
+	assert.Equal(t, 1, store.saves)
+}
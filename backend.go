@@ -0,0 +1,23 @@
+package nest
+
+import "github.com/jtsiros/nest/backend"
+
+var (
+	_ backend.Devices       = (*Client)(nil)
+	_ backend.Thermostats   = (*ThermostatService)(nil)
+	_ backend.Cameras       = (*CameraService)(nil)
+	_ backend.SmokeCoAlarms = (*SmokeCoAlarmService)(nil)
+	_ backend.Structures    = (*StructureService)(nil)
+)
+
+// Backend returns the client's services as a backend.Backend, so code written
+// against the backend interfaces can use the Nest REST API.
+func (nest *Client) Backend() backend.Backend {
+	return backend.Backend{
+		Devices:       nest,
+		Thermostats:   nest.Thermostats,
+		Cameras:       nest.Cameras,
+		SmokeCoAlarms: nest.SmokeCoAlarms,
+		Structures:    nest.Structures,
+	}
+}
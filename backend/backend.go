@@ -0,0 +1,62 @@
+// Package backend defines the device operations shared by the Nest REST API
+// client, the SDM API client and test fakes, so application code can be
+// written once and run against any of them.
+package backend
+
+import (
+	"context"
+	"io"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// Thermostats reads and controls thermostats. It is implemented by
+// nest.ThermostatService and sdm.ThermostatService.
+type Thermostats interface {
+	Get(deviceid string) (*device.Thermostat, error)
+	SetTargetTemperature(deviceid string, scale device.TemperatureScale, target int) error
+	SetTargetTemperatureRange(deviceid string, scale device.TemperatureScale, low, high int) error
+	SetHVACMode(deviceid string, mode device.HvacMode) error
+	EnterEco(deviceid string) error
+	ExitEco(deviceid string) error
+	SetFanTimerDuration(deviceid string, duration int) error
+	SetLabel(deviceid string, label string) error
+	SetTemperatureScale(deviceid string, scale device.TemperatureScale) error
+}
+
+// Cameras reads and controls cameras. It is implemented by nest.CameraService
+// and sdm.CameraService.
+type Cameras interface {
+	Get(deviceid string) (*device.Camera, error)
+	SetStreaming(deviceid string, streaming bool) error
+	SetStreamingForStructure(structureid string, streaming bool) error
+	SetStreamingAll(streaming bool) error
+	Snapshot(ctx context.Context, deviceid string) (io.ReadCloser, string, error)
+	FetchMedia(ctx context.Context, mediaURL string) (io.ReadCloser, string, error)
+}
+
+// SmokeCoAlarms reads Smoke+CO Alarms. It is implemented by
+// nest.SmokeCoAlarmService and sdm.SmokeCoAlarmService.
+type SmokeCoAlarms interface {
+	Get(deviceid string) (*device.SmokeAlarm, error)
+}
+
+// Structures reads structures. It is implemented by nest.StructureService and
+// sdm.StructureService.
+type Structures interface {
+	Get(structureid string) (*device.Structure, error)
+}
+
+// Devices lists every device. It is implemented by nest.Client and sdm.Client.
+type Devices interface {
+	Devices() (*device.Devices, error)
+}
+
+// Backend groups the services of one API.
+type Backend struct {
+	Devices       Devices
+	Thermostats   Thermostats
+	Cameras       Cameras
+	SmokeCoAlarms SmokeCoAlarms
+	Structures    Structures
+}
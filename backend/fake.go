@@ -0,0 +1,359 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/internal/devcopy"
+	"github.com/jtsiros/nest/internal/rules"
+)
+
+// Fake is an in-memory backend for tests. Writes are validated with the rules
+// of the Nest REST API, the same rules nesttest.Server applies, and applied to
+// the stored devices, which can be inspected with Thermostat, Camera and
+// Structure.
+type Fake struct {
+	mu            sync.Mutex
+	thermostats   map[string]*device.Thermostat
+	cameras       map[string]*device.Camera
+	smokeCoAlarms map[string]*device.SmokeAlarm
+	structures    map[string]*device.Structure
+	snapshots     map[string]image
+	media         map[string]image
+}
+
+type image struct {
+	contentType string
+	data        []byte
+}
+
+// NewFake creates a fake backend holding copies of devices and structures.
+func NewFake(devices *device.Devices, structures ...*device.Structure) *Fake {
+	f := &Fake{
+		thermostats:   map[string]*device.Thermostat{},
+		cameras:       map[string]*device.Camera{},
+		smokeCoAlarms: map[string]*device.SmokeAlarm{},
+		structures:    map[string]*device.Structure{},
+		snapshots:     map[string]image{},
+		media:         map[string]image{},
+	}
+	if devices != nil {
+		for id, th := range devices.Thermostats {
+			c := *th
+			f.thermostats[id] = &c
+		}
+		for id, camera := range devices.Cameras {
+			f.cameras[id] = devcopy.Camera(camera)
+		}
+		for id, sa := range devices.SmokeCoAlarms {
+			c := *sa
+			f.smokeCoAlarms[id] = &c
+		}
+	}
+	for _, s := range structures {
+		f.structures[s.StructureID] = devcopy.Structure(s)
+	}
+	return f
+}
+
+// Backend returns the fake's services.
+func (f *Fake) Backend() Backend {
+	return Backend{
+		Devices:       f,
+		Thermostats:   fakeThermostats{f},
+		Cameras:       fakeCameras{f},
+		SmokeCoAlarms: fakeSmokeCoAlarms{f},
+		Structures:    fakeStructures{f},
+	}
+}
+
+// Devices returns copies of all devices.
+func (f *Fake) Devices() (*device.Devices, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := &device.Devices{
+		Thermostats:   map[string]*device.Thermostat{},
+		Cameras:       map[string]*device.Camera{},
+		SmokeCoAlarms: map[string]*device.SmokeAlarm{},
+	}
+	for id, th := range f.thermostats {
+		c := *th
+		d.Thermostats[id] = &c
+	}
+	for id, camera := range f.cameras {
+		d.Cameras[id] = devcopy.Camera(camera)
+	}
+	for id, sa := range f.smokeCoAlarms {
+		c := *sa
+		d.SmokeCoAlarms[id] = &c
+	}
+	return d, nil
+}
+
+// Thermostat returns a copy of the stored thermostat.
+func (f *Fake) Thermostat(deviceid string) (*device.Thermostat, error) {
+	return fakeThermostats{f}.Get(deviceid)
+}
+
+// Camera returns a copy of the stored camera.
+func (f *Fake) Camera(deviceid string) (*device.Camera, error) {
+	return fakeCameras{f}.Get(deviceid)
+}
+
+// Structure returns a copy of the stored structure.
+func (f *Fake) Structure(structureid string) (*device.Structure, error) {
+	return fakeStructures{f}.Get(structureid)
+}
+
+// SetSnapshot sets the image returned by Snapshot for a camera.
+func (f *Fake) SetSnapshot(deviceid, contentType string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.snapshots[deviceid] = image{contentType: contentType, data: data}
+}
+
+// SetMedia sets the media returned by FetchMedia for a media URL, e.g. the
+// image URL of a camera's last event.
+func (f *Fake) SetMedia(mediaURL, contentType string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.media[mediaURL] = image{contentType: contentType, data: data}
+}
+
+func notFound(id string) error {
+	return fmt.Errorf("device not found: %s", id)
+}
+
+type fakeThermostats struct {
+	f *Fake
+}
+
+func (t fakeThermostats) Get(deviceid string) (*device.Thermostat, error) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	th, ok := t.f.thermostats[deviceid]
+	if !ok {
+		return nil, notFound(deviceid)
+	}
+	c := *th
+	return &c, nil
+}
+
+// update applies fn to the stored thermostat.
+func (t fakeThermostats) update(deviceid string, fn func(th *device.Thermostat) error) error {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	th, ok := t.f.thermostats[deviceid]
+	if !ok {
+		return notFound(deviceid)
+	}
+	c := *th
+	if err := fn(&c); err != nil {
+		return err
+	}
+	*th = c
+	return nil
+}
+
+func (t fakeThermostats) SetTargetTemperature(deviceid string, scale device.TemperatureScale, target int) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	return t.update(deviceid, func(th *device.Thermostat) error {
+		return ruleError(rules.SetTargetTemperature(th, scale, float64(target)))
+	})
+}
+
+func (t fakeThermostats) SetTargetTemperatureRange(deviceid string, scale device.TemperatureScale, low, high int) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	if low == 0 && high == 0 {
+		return errors.New("either low or high target must be set above 0")
+	}
+	if low >= high {
+		return errors.New("low value must be less than or equal to high value")
+	}
+	lowT, highT := float64(low), float64(high)
+	return t.update(deviceid, func(th *device.Thermostat) error {
+		return ruleError(rules.SetTargetTemperatureRange(th, scale, &lowT, &highT))
+	})
+}
+
+func (t fakeThermostats) SetHVACMode(deviceid string, mode device.HvacMode) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	}
+	return t.update(deviceid, func(th *device.Thermostat) error {
+		return ruleError(rules.SetHvacMode(th, mode))
+	})
+}
+
+func (t fakeThermostats) EnterEco(deviceid string) error {
+	return t.SetHVACMode(deviceid, device.HvacModeEco)
+}
+
+func (t fakeThermostats) ExitEco(deviceid string) error {
+	th, err := t.Get(deviceid)
+	if err != nil {
+		return err
+	}
+	if th.HvacMode != device.HvacModeEco {
+		return nil
+	}
+	mode := th.PreviousHvacMode
+	if mode == "" || mode == device.HvacModeEco {
+		switch {
+		case th.CanHeat && th.CanCool:
+			mode = device.HvacModeHeatCool
+		case th.CanHeat:
+			mode = device.HvacModeHeat
+		case th.CanCool:
+			mode = device.HvacModeCool
+		default:
+			mode = device.HvacModeOff
+		}
+	}
+	return t.SetHVACMode(deviceid, mode)
+}
+
+func (t fakeThermostats) SetFanTimerDuration(deviceid string, duration int) error {
+	if err := rules.CheckFanTimerDuration(duration); err != nil {
+		return err
+	}
+	return t.update(deviceid, func(th *device.Thermostat) error {
+		if !th.HasFan {
+			return errors.New("thermostat has no fan")
+		}
+		th.FanTimerDuration = duration
+		return nil
+	})
+}
+
+func (t fakeThermostats) SetLabel(deviceid string, label string) error {
+	return t.update(deviceid, func(th *device.Thermostat) error {
+		th.Label = label
+		return nil
+	})
+}
+
+func (t fakeThermostats) SetTemperatureScale(deviceid string, scale device.TemperatureScale) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	return t.update(deviceid, func(th *device.Thermostat) error {
+		th.TemperatureScale = scale
+		return nil
+	})
+}
+
+// ruleError returns err as an error, keeping a nil *rules.Error nil.
+func ruleError(err *rules.Error) error {
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
+type fakeCameras struct {
+	f *Fake
+}
+
+func (c fakeCameras) Get(deviceid string) (*device.Camera, error) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	camera, ok := c.f.cameras[deviceid]
+	if !ok {
+		return nil, notFound(deviceid)
+	}
+	return devcopy.Camera(camera), nil
+}
+
+func (c fakeCameras) SetStreaming(deviceid string, streaming bool) error {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	camera, ok := c.f.cameras[deviceid]
+	if !ok {
+		return notFound(deviceid)
+	}
+	camera.IsStreaming = streaming
+	return nil
+}
+
+func (c fakeCameras) SetStreamingForStructure(structureid string, streaming bool) error {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	for _, camera := range c.f.cameras {
+		if camera.StructureID == structureid {
+			camera.IsStreaming = streaming
+		}
+	}
+	return nil
+}
+
+func (c fakeCameras) SetStreamingAll(streaming bool) error {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	for _, camera := range c.f.cameras {
+		camera.IsStreaming = streaming
+	}
+	return nil
+}
+
+func (c fakeCameras) Snapshot(ctx context.Context, deviceid string) (io.ReadCloser, string, error) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if _, ok := c.f.cameras[deviceid]; !ok {
+		return nil, "", notFound(deviceid)
+	}
+	s, ok := c.f.snapshots[deviceid]
+	if !ok {
+		return nil, "", fmt.Errorf("camera %s has no snapshot url", deviceid)
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.data)), s.contentType, nil
+}
+
+func (c fakeCameras) FetchMedia(ctx context.Context, mediaURL string) (io.ReadCloser, string, error) {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	m, ok := c.f.media[mediaURL]
+	if !ok {
+		return nil, "", fmt.Errorf("no media at %s", mediaURL)
+	}
+	return ioutil.NopCloser(bytes.NewReader(m.data)), m.contentType, nil
+}
+
+type fakeSmokeCoAlarms struct {
+	f *Fake
+}
+
+func (s fakeSmokeCoAlarms) Get(deviceid string) (*device.SmokeAlarm, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	sa, ok := s.f.smokeCoAlarms[deviceid]
+	if !ok {
+		return nil, notFound(deviceid)
+	}
+	c := *sa
+	return &c, nil
+}
+
+type fakeStructures struct {
+	f *Fake
+}
+
+func (s fakeStructures) Get(structureid string) (*device.Structure, error) {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	st, ok := s.f.structures[structureid]
+	if !ok {
+		return nil, fmt.Errorf("structure not found: %s", structureid)
+	}
+	return devcopy.Structure(st), nil
+}
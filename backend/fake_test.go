@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFake() *Fake {
+	return NewFake(&device.Devices{
+		Thermostats: map[string]*device.Thermostat{
+			"t1": {DeviceID: "t1", HvacMode: device.HvacModeHeat, CanHeat: true, CanCool: true, HasFan: true},
+		},
+		Cameras: map[string]*device.Camera{
+			"c1": {DeviceID: "c1", StructureID: "s1"},
+			"c2": {DeviceID: "c2", StructureID: "s2"},
+		},
+		SmokeCoAlarms: map[string]*device.SmokeAlarm{
+			"p1": {DeviceID: "p1", CoAlarmState: device.AlarmStateOK},
+		},
+	}, &device.Structure{StructureID: "s1", Name: "Home"})
+}
+
+func Test_FakeThermostats(t *testing.T) {
+	f := newTestFake()
+	th := f.Backend().Thermostats
+
+	assert.NoError(t, th.SetTargetTemperature("t1", device.Fahrenheit, 70))
+	got, _ := f.Thermostat("t1")
+	assert.Equal(t, 70, got.TargetTemperatureF)
+	assert.Equal(t, 21.0, got.TargetTemperatureC)
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"unknown device", th.SetLabel("unknown", "Hallway")},
+		{"bad scale", th.SetTargetTemperature("t1", device.TemperatureScale("K"), 70)},
+		{"range in heat mode", th.SetTargetTemperatureRange("t1", device.Celsius, 18, 22)},
+		{"inverted range", th.SetTargetTemperatureRange("t1", device.Celsius, 22, 18)},
+		{"fan duration", th.SetFanTimerDuration("t1", 10)},
+		{"unsupported fan duration", th.SetFanTimerDuration("t1", 75)},
+		{"too hot", th.SetTargetTemperature("t1", device.Fahrenheit, 95)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.err)
+		})
+	}
+
+	assert.NoError(t, th.SetHVACMode("t1", device.HvacModeHeatCool))
+	assert.NoError(t, th.SetTargetTemperatureRange("t1", device.Celsius, 18, 22))
+	got, _ = f.Thermostat("t1")
+	assert.Equal(t, 18.0, got.TargetTemperatureLowC)
+	assert.Equal(t, 72, got.TargetTemperatureHighF)
+	assert.Error(t, th.SetTargetTemperature("t1", device.Celsius, 20), "target temperature requires heat or cool mode")
+}
+
+func Test_FakeEco(t *testing.T) {
+	f := newTestFake()
+	th := f.Backend().Thermostats
+
+	assert.NoError(t, th.EnterEco("t1"))
+	got, _ := f.Thermostat("t1")
+	assert.Equal(t, device.HvacModeEco, got.HvacMode)
+	assert.Equal(t, device.HvacModeHeat, got.PreviousHvacMode)
+
+	assert.NoError(t, th.ExitEco("t1"))
+	got, _ = f.Thermostat("t1")
+	assert.Equal(t, device.HvacModeHeat, got.HvacMode)
+
+	f.thermostats["t1"].IsUsingEmergencyHeat = true
+	assert.EqualError(t, th.SetHVACMode("t1", device.HvacModeCool), "Cannot change HVAC mode while using emergency heat")
+	assert.EqualError(t, th.SetTargetTemperature("t1", device.Fahrenheit, 70), "Cannot change target temperature while using emergency heat")
+}
+
+func Test_FakeCameras(t *testing.T) {
+	f := newTestFake()
+	cameras := f.Backend().Cameras
+
+	assert.NoError(t, cameras.SetStreamingForStructure("s1", true))
+	c1, _ := f.Camera("c1")
+	c2, _ := f.Camera("c2")
+	assert.True(t, c1.IsStreaming)
+	assert.False(t, c2.IsStreaming)
+
+	_, _, err := cameras.Snapshot(context.Background(), "c1")
+	assert.EqualError(t, err, "camera c1 has no snapshot url")
+
+	f.SetSnapshot("c1", "image/jpeg", []byte("jpeg"))
+	r, contentType, err := cameras.Snapshot(context.Background(), "c1")
+	if assert.NoError(t, err) {
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "jpeg", string(b))
+		assert.Equal(t, "image/jpeg", contentType)
+	}
+
+	_, _, err = cameras.FetchMedia(context.Background(), "https://nest/image")
+	assert.EqualError(t, err, "no media at https://nest/image")
+
+	f.SetMedia("https://nest/image", "image/gif", []byte("gif"))
+	r, contentType, err = cameras.FetchMedia(context.Background(), "https://nest/image")
+	if assert.NoError(t, err) {
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "gif", string(b))
+		assert.Equal(t, "image/gif", contentType)
+	}
+}
+
+func Test_FakeDevices(t *testing.T) {
+	f := newTestFake()
+	b := f.Backend()
+
+	devices, err := b.Devices.Devices()
+	assert.NoError(t, err)
+	assert.Len(t, devices.Thermostats, 1)
+	assert.Len(t, devices.Cameras, 2)
+
+	devices.Thermostats["t1"].Label = "changed"
+	th, _ := b.Thermostats.Get("t1")
+	assert.Empty(t, th.Label, "returned devices should be copies")
+
+	sa, err := b.SmokeCoAlarms.Get("p1")
+	assert.NoError(t, err)
+	assert.Equal(t, device.AlarmStateOK, sa.CoAlarmState)
+
+	s, err := b.Structures.Get("s1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Home", s.Name)
+	_, err = b.Structures.Get("s2")
+	assert.EqualError(t, err, "structure not found: s2")
+}
+
+func Test_FakeDeepCopies(t *testing.T) {
+	camera := &device.Camera{
+		DeviceID:      "c1",
+		ActivityZones: []*device.ActivityZone{{ID: 1, Name: "Driveway"}},
+		LastEvent:     &device.CameraEvent{HasMotion: true, ActivityZoneIDs: []string{"1"}},
+	}
+	structure := &device.Structure{StructureID: "s1", Cameras: []string{"c1"}, Wheres: map[string]*device.Where{"w1": {WhereID: "w1", Name: "Den"}}}
+	f := NewFake(&device.Devices{Cameras: map[string]*device.Camera{"c1": camera}}, structure)
+
+	// changing the input does not change the stored devices
+	camera.ActivityZones[0].Name = "changed"
+	camera.LastEvent.ActivityZoneIDs[0] = "changed"
+	structure.Cameras[0] = "changed"
+	structure.Wheres["w1"].Name = "changed"
+
+	c, _ := f.Camera("c1")
+	assert.Equal(t, "Driveway", c.ActivityZones[0].Name)
+	assert.Equal(t, []string{"1"}, c.LastEvent.ActivityZoneIDs)
+	s, _ := f.Structure("s1")
+	assert.Equal(t, []string{"c1"}, s.Cameras)
+	assert.Equal(t, "Den", s.Wheres["w1"].Name)
+
+	// nor does changing the returned devices
+	c.ActivityZones[0].Name = "changed"
+	c.LastEvent.HasMotion = false
+	s.Wheres["w1"].Name = "changed"
+	devices, _ := f.Devices()
+	devices.Cameras["c1"].LastEvent.ActivityZoneIDs[0] = "changed"
+
+	c, _ = f.Camera("c1")
+	assert.Equal(t, "Driveway", c.ActivityZones[0].Name)
+	assert.True(t, c.LastEvent.HasMotion)
+	assert.Equal(t, []string{"1"}, c.LastEvent.ActivityZoneIDs)
+	s, _ = f.Structure("s1")
+	assert.Equal(t, "Den", s.Wheres["w1"].Name)
+}
@@ -0,0 +1,27 @@
+package nest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jtsiros/nest/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Backend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, apiResponse)
+	}))
+	defer ts.Close()
+
+	api, _ := NewClient(config.Config{APIURL: ts.URL}, ts.Client())
+	b := api.Backend()
+	assert.Equal(t, api.Thermostats, b.Thermostats)
+	assert.Equal(t, api.Structures, b.Structures)
+
+	devices, err := b.Devices.Devices()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, devices.Thermostats)
+}
@@ -0,0 +1,190 @@
+package nest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// CameraEventKind describes whether a camera event started or ended.
+type CameraEventKind string
+
+// Camera event kinds
+const (
+	CameraEventStarted CameraEventKind = "started"
+	CameraEventEnded   CameraEventKind = "ended"
+)
+
+// CameraNotification is emitted by WatchCameraEvents when a camera event
+// starts or ends. Camera is the camera state that carried the event.
+type CameraNotification struct {
+	DeviceID string
+	Kind     CameraEventKind
+	Event    device.CameraEvent
+	Camera   *device.Camera
+}
+
+func (n CameraNotification) String() string {
+	return fmt.Sprintf("camera %s event %s at %s", n.DeviceID, n.Kind, n.Event.StartTime.Format(time.RFC3339))
+}
+
+type cameraEventState struct {
+	start time.Time
+	ended bool
+}
+
+// WatchCameraEvents reads camera events from a Stream and emits a notification
+// when a new last_event starts and when it ends. Repeated puts of the same
+// event are ignored. The last event of a camera when it is first seen is only
+// reported if it is still in progress. The returned channel is closed when
+// events is closed or ctx is done.
+func WatchCameraEvents(ctx context.Context, events <-chan Event) <-chan CameraNotification {
+	out := make(chan CameraNotification)
+	go func() {
+		defer close(out)
+		seen := map[string]*cameraEventState{}
+		for {
+			var event Event
+			var ok bool
+			select {
+			case event, ok = <-events:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			camera, ok := cameraFromEvent(event)
+			if !ok || camera.LastEvent == nil || camera.LastEvent.StartTime.IsZero() {
+				continue
+			}
+			for _, n := range cameraTransitions(seen, camera) {
+				select {
+				case out <- n:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// cameraTransitions compares the last event of a camera with the previously
+// seen event and returns the notifications to emit.
+func cameraTransitions(seen map[string]*cameraEventState, camera *device.Camera) []CameraNotification {
+	e := camera.LastEvent
+	ended := !e.InProgress()
+	notify := func(kind CameraEventKind) CameraNotification {
+		return CameraNotification{DeviceID: camera.DeviceID, Kind: kind, Event: *e, Camera: camera}
+	}
+
+	prev, ok := seen[camera.DeviceID]
+	seen[camera.DeviceID] = &cameraEventState{start: e.StartTime, ended: ended}
+	switch {
+	case !ok:
+		if ended {
+			return nil
+		}
+		return []CameraNotification{notify(CameraEventStarted)}
+	case !prev.start.Equal(e.StartTime):
+		if ended {
+			return []CameraNotification{notify(CameraEventStarted), notify(CameraEventEnded)}
+		}
+		return []CameraNotification{notify(CameraEventStarted)}
+	case !prev.ended && ended:
+		return []CameraNotification{notify(CameraEventEnded)}
+	}
+	seen[camera.DeviceID].ended = prev.ended || ended
+	return nil
+}
+
+// cameraFromEvent returns the camera carried by a stream event. The device id
+// from the event path is used when the data omits it.
+func cameraFromEvent(event Event) (*device.Camera, bool) {
+	et, id, data, err := event.GetEvent()
+	if err != nil || et != Cameras {
+		return nil, false
+	}
+	camera, ok := data.(*device.Camera)
+	if !ok || camera == nil {
+		return nil, false
+	}
+	if camera.DeviceID == "" {
+		camera.DeviceID = id
+	}
+	return camera, true
+}
+
+// CameraEventFilter selects camera notifications, e.g. person events in the
+// "Driveway" activity zone. Zones match activity zone names (case-insensitive)
+// or ids; an event matches when it is in any of the zones. When any of Person,
+// Motion or Sound is set, the event must have at least one of the selected
+// kinds of activity. The zero filter matches every notification.
+type CameraEventFilter struct {
+	Zones  []string
+	Person bool
+	Motion bool
+	Sound  bool
+}
+
+// Match reports whether the notification is selected by the filter.
+func (f CameraEventFilter) Match(n CameraNotification) bool {
+	if f.Person || f.Motion || f.Sound {
+		e := n.Event
+		if !(f.Person && e.HasPerson || f.Motion && e.HasMotion || f.Sound && e.HasSound) {
+			return false
+		}
+	}
+	if len(f.Zones) == 0 {
+		return true
+	}
+	for _, id := range n.Event.ActivityZoneIDs {
+		name := ""
+		if n.Camera != nil {
+			if z, ok := n.Camera.ActivityZone(id); ok {
+				name = z.Name
+			}
+		}
+		for _, zone := range f.Zones {
+			if zone == id || (name != "" && strings.EqualFold(zone, name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterCameraEvents returns a channel receiving the notifications from in
+// that match the filter. The returned channel is closed when in is closed or
+// ctx is done.
+func FilterCameraEvents(ctx context.Context, in <-chan CameraNotification, f CameraEventFilter) <-chan CameraNotification {
+	out := make(chan CameraNotification)
+	go func() {
+		defer close(out)
+		for {
+			var n CameraNotification
+			var ok bool
+			select {
+			case n, ok = <-in:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			if !f.Match(n) {
+				continue
+			}
+			select {
+			case out <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
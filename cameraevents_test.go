@@ -0,0 +1,111 @@
+package nest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func cameraEvent(id, start, end string) Event {
+	return Event{
+		name: []byte("put"),
+		data: []byte(fmt.Sprintf(`{"path":"/devices/cameras/%s","data":{"last_event":{"has_motion":true,"start_time":"%s","end_time":"%s"}}}`, id, start, end)),
+	}
+}
+
+func Test_WatchCameraEvents(t *testing.T) {
+	events := make(chan Event)
+	out := WatchCameraEvents(context.Background(), events)
+
+	go func() {
+		// historical event when the stream opens is not reported
+		events <- cameraEvent("1", "2020-03-24T10:00:00Z", "2020-03-24T10:01:00Z")
+		// new event in progress, repeated
+		events <- cameraEvent("1", "2020-03-24T11:00:00Z", "2020-03-24T10:01:00Z")
+		events <- cameraEvent("1", "2020-03-24T11:00:00Z", "2020-03-24T10:01:00Z")
+		// event ends, repeated
+		events <- cameraEvent("1", "2020-03-24T11:00:00Z", "2020-03-24T11:02:00Z")
+		events <- cameraEvent("1", "2020-03-24T11:00:00Z", "2020-03-24T11:02:00Z")
+		// in progress on first sight
+		events <- cameraEvent("2", "2020-03-24T11:30:00Z", "2020-03-24T11:00:00Z")
+		// an event that started and ended between puts
+		events <- cameraEvent("1", "2020-03-24T12:00:00Z", "2020-03-24T12:01:00Z")
+		events <- Event{name: []byte(KeepAlive)}
+		close(events)
+	}()
+
+	var got []string
+	for n := range out {
+		got = append(got, n.String())
+	}
+	assert.Equal(t, []string{
+		"camera 1 event started at 2020-03-24T11:00:00Z",
+		"camera 1 event ended at 2020-03-24T11:00:00Z",
+		"camera 2 event started at 2020-03-24T11:30:00Z",
+		"camera 1 event started at 2020-03-24T12:00:00Z",
+		"camera 1 event ended at 2020-03-24T12:00:00Z",
+	}, got)
+}
+
+func Test_CameraEventFilter(t *testing.T) {
+	camera := &device.Camera{ActivityZones: []*device.ActivityZone{{Name: "Driveway", ID: 1}, {Name: "Porch", ID: 2}}}
+	notification := func(person, motion bool, zones ...string) CameraNotification {
+		return CameraNotification{
+			DeviceID: "123",
+			Camera:   camera,
+			Event:    device.CameraEvent{HasPerson: person, HasMotion: motion, ActivityZoneIDs: zones},
+		}
+	}
+
+	driveway := CameraEventFilter{Zones: []string{"driveway"}, Person: true}
+	tt := []struct {
+		f        CameraEventFilter
+		n        CameraNotification
+		expected bool
+	}{
+		{CameraEventFilter{}, notification(false, true), true},
+		{driveway, notification(true, true, "1"), true},
+		{driveway, notification(true, false, "2", "1"), true},
+		{driveway, notification(false, true, "1"), false},
+		{driveway, notification(true, false, "2"), false},
+		{driveway, notification(true, false), false},
+		{CameraEventFilter{Zones: []string{"2"}}, notification(false, true, "2"), true},
+		{CameraEventFilter{Person: true, Motion: true}, notification(false, true), true},
+		{CameraEventFilter{Sound: true}, notification(true, true), false},
+	}
+
+	for i, tc := range tt {
+		assert.Equal(t, tc.expected, tc.f.Match(tc.n), "case %d", i)
+	}
+}
+
+func Test_WatchCameraEventsContext(t *testing.T) {
+	events := make(chan Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := WatchCameraEvents(ctx, events)
+
+	events <- cameraEvent("1", "2020-03-24T11:00:00Z", "2020-03-24T10:01:00Z")
+	cancel()
+	for range out {
+	}
+}
+
+func Test_FilterCameraEvents(t *testing.T) {
+	in := make(chan CameraNotification)
+	out := FilterCameraEvents(context.Background(), in, CameraEventFilter{Person: true})
+
+	go func() {
+		in <- CameraNotification{DeviceID: "1", Event: device.CameraEvent{HasMotion: true}}
+		in <- CameraNotification{DeviceID: "2", Event: device.CameraEvent{HasPerson: true}}
+		close(in)
+	}()
+
+	var got []string
+	for n := range out {
+		got = append(got, n.DeviceID)
+	}
+	assert.Equal(t, []string{"2"}, got)
+}
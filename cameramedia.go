@@ -0,0 +1,199 @@
+package nest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultMaxMediaSize is the maximum number of bytes read from a camera
+// snapshot or event image when Client.MaxMediaSize is not set.
+const DefaultMaxMediaSize int64 = 10 << 20
+
+// ErrMediaTooLarge is returned when camera media exceeds the maximum size.
+var ErrMediaTooLarge = errors.New("media exceeds maximum size")
+
+// Snapshot fetches a current image from the camera. The caller must close the
+// returned reader. The content type of the image is returned along with it.
+// See https://developers.nest.com/reference/api-camera#snapshot_url
+//
+func (svc *CameraService) Snapshot(ctx context.Context, deviceid string) (io.ReadCloser, string, error) {
+	camera, err := svc.Get(deviceid)
+	if err != nil {
+		return nil, "", err
+	}
+	if camera.SnapshotURL == "" {
+		return nil, "", fmt.Errorf("camera %s has no snapshot url", deviceid)
+	}
+	return svc.FetchMedia(ctx, camera.SnapshotURL)
+}
+
+// EventImage fetches the image of the camera's last event. See Snapshot.
+// See https://developers.nest.com/reference/api-camera#image_url
+//
+func (svc *CameraService) EventImage(ctx context.Context, deviceid string) (io.ReadCloser, string, error) {
+	camera, err := svc.Get(deviceid)
+	if err != nil {
+		return nil, "", err
+	}
+	if camera.LastEvent == nil || camera.LastEvent.ImageURL == "" {
+		return nil, "", fmt.Errorf("camera %s has no event image url", deviceid)
+	}
+	return svc.FetchMedia(ctx, camera.LastEvent.ImageURL)
+}
+
+// EventAnimation fetches the animated GIF of the camera's last event. See Snapshot.
+// See https://developers.nest.com/reference/api-camera#animated_image_url
+//
+func (svc *CameraService) EventAnimation(ctx context.Context, deviceid string) (io.ReadCloser, string, error) {
+	camera, err := svc.Get(deviceid)
+	if err != nil {
+		return nil, "", err
+	}
+	if camera.LastEvent == nil || camera.LastEvent.AnimatedImageURL == "" {
+		return nil, "", fmt.Errorf("camera %s has no event animation url", deviceid)
+	}
+	return svc.FetchMedia(ctx, camera.LastEvent.AnimatedImageURL)
+}
+
+// FetchMedia fetches a camera media URL, such as a snapshot or event image URL
+// taken from a camera received on a stream. Reads are limited to the client's
+// MaxMediaSize. Media URLs are signed, so the client's credentials are only
+// sent to the API host; other hosts are fetched with the client's MediaClient.
+func (svc *CameraService) FetchMedia(ctx context.Context, mediaURL string) (io.ReadCloser, string, error) {
+	req, err := http.NewRequest(http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := svc.client.mediaClient()
+	if req.URL.Host == svc.client.baseURL.Host {
+		client = svc.client.httpClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	max := svc.client.maxMediaSize()
+	if resp.ContentLength > max {
+		resp.Body.Close()
+		return nil, "", ErrMediaTooLarge
+	}
+	body := &limitedReadCloser{rc: resp.Body, remaining: max}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// mediaClient returns the client used to fetch media from hosts other than
+// the API host: MediaClient when set, otherwise the client's HTTP client
+// without its OAuth2 transport.
+func (nest *Client) mediaClient() *http.Client {
+	if nest.MediaClient != nil {
+		return nest.MediaClient
+	}
+	if nest.httpClient == nil {
+		return http.DefaultClient
+	}
+	c := *nest.httpClient
+	if t, ok := c.Transport.(*oauth2.Transport); ok {
+		c.Transport = t.Base
+	}
+	return &c
+}
+
+// maxMediaSize returns the maximum number of bytes of camera media read by the client.
+func (nest *Client) maxMediaSize() int64 {
+	if nest.MaxMediaSize > 0 {
+		return nest.MaxMediaSize
+	}
+	return DefaultMaxMediaSize
+}
+
+// limitedReadCloser returns ErrMediaTooLarge once more than remaining bytes are read.
+type limitedReadCloser struct {
+	rc        io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrMediaTooLarge
+	}
+	// read one byte past the limit to detect oversized media
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.rc.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrMediaTooLarge
+	}
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.rc.Close()
+}
+
+// SaveMedia copies media to w and closes it.
+func SaveMedia(w io.Writer, media io.ReadCloser) (int64, error) {
+	defer media.Close()
+	return io.Copy(w, media)
+}
+
+// SaveMediaToDir writes media to a new file in dir and closes it. The file is
+// named after prefix and the UTC timestamp t, with an extension derived from
+// contentType, e.g. "front-door-20200324T110000Z.jpg". The path of the file is
+// returned. Partially written files are removed. The prefix must not contain
+// path separators.
+func SaveMediaToDir(dir, prefix string, t time.Time, contentType string, media io.ReadCloser) (string, error) {
+	defer media.Close()
+
+	if strings.ContainsAny(prefix, `/\`) || strings.ContainsRune(prefix, filepath.Separator) {
+		return "", fmt.Errorf("invalid media file prefix: %q", prefix)
+	}
+
+	name := fmt.Sprintf("%s-%s%s", prefix, t.UTC().Format("20060102T150405Z"), mediaExtension(contentType))
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, media); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func mediaExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ".bin"
+	}
+	switch strings.ToLower(mediaType) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/png":
+		return ".png"
+	}
+	return ".bin"
+}
@@ -0,0 +1,177 @@
+package nest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func newMediaServer() *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/devices/cameras/123":
+			fmt.Fprintf(w, `{"device_id":"123","snapshot_url":"%[1]s/snapshot","last_event":{"image_url":"%[1]s/image","animated_image_url":"%[1]s/large"}}`, ts.URL)
+		case "/devices/cameras/456":
+			fmt.Fprint(w, `{"device_id":"456"}`)
+		case "/snapshot":
+			w.Header().Set("Content-Type", "image/jpeg")
+			fmt.Fprint(w, "jpeg data")
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "png data")
+		case "/large":
+			w.Header().Set("Content-Type", "image/gif")
+			// no content length so the limit is enforced while reading
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, strings.Repeat("g", 64))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return ts
+}
+
+func Test_Snapshot(t *testing.T) {
+	ts := newMediaServer()
+	defer ts.Close()
+	svc := NewCameraService(newTestClientWithServer(ts))
+
+	r, contentType, err := svc.Snapshot(context.Background(), "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := SaveMedia(&buf, r)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), n)
+	assert.Equal(t, "jpeg data", buf.String())
+	assert.Equal(t, "image/jpeg", contentType)
+
+	r, contentType, err = svc.EventImage(context.Background(), "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "png data", string(b))
+	assert.Equal(t, "image/png", contentType)
+
+	_, _, err = svc.Snapshot(context.Background(), "456")
+	assert.EqualError(t, err, "camera 456 has no snapshot url")
+	_, _, err = svc.EventAnimation(context.Background(), "456")
+	assert.EqualError(t, err, "camera 456 has no event animation url")
+	_, _, err = svc.FetchMedia(context.Background(), ts.URL+"/missing")
+	assert.EqualError(t, err, "expected status code 200, got 404")
+}
+
+func Test_MediaSizeLimit(t *testing.T) {
+	ts := newMediaServer()
+	defer ts.Close()
+	c := newTestClientWithServer(ts)
+	c.MaxMediaSize = 8
+	svc := NewCameraService(c)
+
+	_, _, err := svc.Snapshot(context.Background(), "123")
+	assert.Equal(t, ErrMediaTooLarge, err, "content length above the limit should be rejected")
+
+	r, _, err := svc.EventAnimation(context.Background(), "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	assert.Equal(t, ErrMediaTooLarge, err)
+	assert.Len(t, b, 8)
+}
+
+func Test_SaveMediaToDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	at := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	tt := []struct {
+		contentType string
+		name        string
+	}{
+		{"image/jpeg", "den-20200324T110000Z.jpg"},
+		{"image/gif; charset=binary", "den-20200324T110001Z.gif"},
+		{"", "den-20200324T110002Z.bin"},
+	}
+
+	for i, tc := range tt {
+		path, err := SaveMediaToDir(dir, "den", at.Add(time.Duration(i)*time.Second), tc.contentType, ioutil.NopCloser(strings.NewReader("data")))
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, tc.name), path)
+	}
+
+	_, err = SaveMediaToDir(dir, "den", at, "image/jpeg", ioutil.NopCloser(strings.NewReader("data")))
+	assert.Error(t, err, "existing files should not be overwritten")
+
+	for _, prefix := range []string{"../den", "a/b", `..\den`} {
+		_, err = SaveMediaToDir(dir, prefix, at, "image/jpeg", ioutil.NopCloser(strings.NewReader("data")))
+		assert.EqualError(t, err, fmt.Sprintf("invalid media file prefix: %q", prefix))
+	}
+}
+
+// countingTransport counts the requests made through it.
+type countingTransport struct {
+	base     http.RoundTripper
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return t.base.RoundTrip(req)
+}
+
+func Test_FetchMediaCredentials(t *testing.T) {
+	var auth []string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "image/jpeg")
+		fmt.Fprint(w, "jpeg data")
+	}))
+	defer cdn.Close()
+	api := httptest.NewServer(cdn.Config.Handler)
+	defer api.Close()
+
+	base := &countingTransport{base: http.DefaultTransport}
+	c := newTestClientWithServer(api)
+	c.httpClient = &http.Client{Transport: &oauth2.Transport{
+		Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "c.token"}),
+		Base:   base,
+	}}
+	svc := NewCameraService(c)
+
+	for _, u := range []string{cdn.URL + "/snapshot", api.URL + "/snapshot"} {
+		r, _, err := svc.FetchMedia(context.Background(), u)
+		if assert.NoError(t, err) {
+			r.Close()
+		}
+	}
+	assert.Equal(t, []string{"", "Bearer c.token"}, auth, "credentials should only be sent to the API host")
+	assert.Equal(t, 2, base.requests, "media should be fetched with the client's base transport")
+
+	media := &countingTransport{base: http.DefaultTransport}
+	c.MediaClient = &http.Client{Transport: media}
+	r, _, err := svc.FetchMedia(context.Background(), cdn.URL+"/snapshot")
+	if assert.NoError(t, err) {
+		r.Close()
+	}
+	assert.Equal(t, 1, media.requests, "media should be fetched with the configured media client")
+}
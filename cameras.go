@@ -2,7 +2,10 @@ package nest
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/jtsiros/nest/config"
 	"github.com/jtsiros/nest/device"
@@ -14,6 +17,23 @@ import (
 // certain values are provided.
 type CameraService service
 
+// DeviceErrors maps device ids to the error returned for each device by a bulk operation.
+type DeviceErrors map[string]error
+
+func (e DeviceErrors) Error() string {
+	ids := make([]string, 0, len(e))
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, 0, len(e))
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", id, e[id]))
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // NewCameraService creates a new service.
 func NewCameraService(client *Client) *CameraService {
 	u := &url.URL{Path: "/devices/cameras"}
@@ -33,12 +53,60 @@ func (svc *CameraService) Get(deviceid string) (*device.Camera, error) {
 	return &camera, err
 }
 
+// SetStreaming turns streaming on or off for a camera.
+// See https://developers.nest.com/reference/api-camera#is_streaming
+//
+func (svc *CameraService) SetStreaming(deviceid string, streaming bool) error {
+	return svc.requestWithValues(http.MethodPut, deviceid, values{"is_streaming": streaming})
+}
+
+// SetStreamingForStructure turns streaming on or off for every camera in a structure.
+// All cameras are attempted; failures are returned as DeviceErrors.
+func (svc *CameraService) SetStreamingForStructure(structureid string, streaming bool) error {
+	return svc.setStreamingWhere(streaming, func(c *device.Camera) bool {
+		return c.StructureID == structureid
+	})
+}
+
+// SetStreamingAll turns streaming on or off for every camera on the account.
+// All cameras are attempted; failures are returned as DeviceErrors.
+func (svc *CameraService) SetStreamingAll(streaming bool) error {
+	return svc.setStreamingWhere(streaming, func(c *device.Camera) bool {
+		return true
+	})
+}
+
+func (svc *CameraService) setStreamingWhere(streaming bool, match func(*device.Camera) bool) error {
+	devices, err := svc.client.Devices()
+	if err != nil {
+		return err
+	}
+
+	errs := DeviceErrors{}
+	for id, camera := range devices.Cameras {
+		if !match(camera) || camera.IsStreaming == streaming {
+			continue
+		}
+		if err := svc.SetStreaming(id, streaming); err != nil {
+			errs[id] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 // Stream opens an event stream to monitor changes on the Camera
 // https://developers.nest.com/guides/api/rest-streaming-guide
 //
 func (svc *CameraService) Stream(deviceID string) (*Stream, error) {
 	rel := &url.URL{Path: fmt.Sprintf("%s/%s", svc.apiURL.String(), deviceID)}
-	return NewStream(&config.Config{
+	return svc.client.trackStream(NewStream(&config.Config{
 		APIURL: svc.client.baseURL.ResolveReference(rel).String(),
-	}, svc.client.httpClient)
+	}, svc.client.httpClient))
+}
+
+func (svc *CameraService) requestWithValues(method string, path string, values map[string]interface{}) error {
+	return svc.client.requestWithValues(method, fmt.Sprintf("%s/%s", svc.apiURL.String(), path), values)
 }
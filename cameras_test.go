@@ -1,7 +1,12 @@
 package nest
 
 import (
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -60,3 +65,64 @@ func Test_StreamCameraDevice(t *testing.T) {
 	assert.Equal(t, []byte("123"), event.name)
 	assert.Equal(t, []byte("456"), event.data)
 }
+
+func Test_SetStreaming(t *testing.T) {
+	tt := []struct {
+		deviceID  string
+		s         *CameraService
+		streaming bool
+		err       string
+	}{
+		{"123", NewCameraService(newTestClient(`{"is_streaming": true}`, http.StatusOK)), true, ""},
+		{"123", NewCameraService(newTestClient(`{"is_streaming": false}`, http.StatusOK)), false, ""},
+		{"456", NewCameraService(newTestClient(`{"message":"Invalid camera id: 456"}`, http.StatusBadRequest)), true, "Invalid camera id: 456"},
+	}
+
+	for _, tc := range tt {
+		err := tc.s.SetStreaming(tc.deviceID, tc.streaming)
+		if tc.err != "" {
+			assert.Equal(t, tc.err, err.Error())
+			assert.Equal(t, http.StatusBadRequest, err.(Error).StatusCode)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func Test_SetStreamingForStructure(t *testing.T) {
+	var puts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, `{"devices":{"cameras":{
+				"1":{"device_id":"1","structure_id":"a","is_streaming":false},
+				"2":{"device_id":"2","structure_id":"a","is_streaming":true},
+				"3":{"device_id":"3","structure_id":"b","is_streaming":false},
+				"4":{"device_id":"4","structure_id":"a","is_streaming":false}}}}`)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		puts = append(puts, r.URL.Path+" "+strings.TrimSpace(string(b)))
+		if strings.HasSuffix(r.URL.Path, "/4") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":"Camera is offline"}`)
+			return
+		}
+		fmt.Fprint(w, string(b))
+	}))
+	defer ts.Close()
+
+	svc := NewCameraService(newTestClientWithServer(ts))
+	err := svc.SetStreamingForStructure("a", true)
+	assert.EqualError(t, err, "4: Camera is offline")
+	assert.Contains(t, err.(DeviceErrors), "4")
+
+	sort.Strings(puts)
+	assert.Equal(t, []string{
+		`/devices/cameras/1 {"is_streaming":true}`,
+		`/devices/cameras/4 {"is_streaming":true}`,
+	}, puts)
+
+	puts = nil
+	assert.NoError(t, svc.SetStreamingAll(false))
+	assert.Equal(t, []string{`/devices/cameras/2 {"is_streaming":false}`}, puts)
+}
@@ -0,0 +1,278 @@
+// Package cassette records HTTP interactions with the Nest API to files and
+// replays them, so client tests can run against real responses without
+// network access. A Recorder captures requests and responses, including
+// event streams with the timing of each chunk, and redacts credentials. A
+// Replayer serves a recorded cassette to nest.NewClient and nest.NewStream.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jtsiros/nest/internal/fileutil"
+)
+
+// Redacted replaces credentials in recorded interactions.
+const Redacted = "REDACTED"
+
+// secretFields are the fields of OAuth2 token requests and responses that hold
+// credentials.
+var secretFields = []string{"access_token", "refresh_token", "id_token", "client_secret", "code"}
+
+// Cassette is a recorded sequence of HTTP interactions.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. The URL, headers and body are redacted.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Response is a recorded response. Its headers and body are redacted.
+// Streamed responses, such as event streams, are recorded as chunks instead of
+// a body.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+	Chunks     []Chunk     `json:"chunks,omitempty"`
+}
+
+// Chunk is part of a streamed response body. Delay is the time between the
+// previous chunk, or the response headers, and this chunk.
+type Chunk struct {
+	Delay time.Duration `json:"delay"`
+	Data  Body          `json:"data"`
+}
+
+// Body is recorded as text when it is valid UTF-8 and as base64 otherwise, so
+// cassettes of JSON APIs stay readable.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(struct {
+		Base64 string `json:"base64"`
+	}{base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// Load reads a cassette from a file.
+func Load(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to a file.
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(path, b, 0644)
+}
+
+// redactURL replaces the auth query parameter, which the Nest API accepts in
+// place of an Authorization header, with Redacted.
+func redactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	q := u.Query()
+	if _, ok := q["auth"]; !ok {
+		return rawurl
+	}
+	q.Set("auth", Redacted)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// redactHeader returns a copy of h with credentials redacted.
+func redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	c := http.Header{}
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	if _, ok := c["Authorization"]; ok {
+		c.Set("Authorization", Redacted)
+	}
+	if loc := c.Get("Location"); loc != "" {
+		c.Set("Location", redactURL(loc))
+	}
+	return c
+}
+
+// redactBody replaces the credentials in a form-encoded or JSON body, such as
+// the authorization code, client secret and tokens of an OAuth2 token request
+// and response, with Redacted. Other bodies are returned unchanged.
+func redactBody(h http.Header, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" {
+		return redactForm(body)
+	}
+	return redactJSON(body)
+}
+
+func redactForm(body []byte) []byte {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return body
+	}
+	redacted := false
+	for _, field := range secretFields {
+		if _, ok := values[field]; ok {
+			values.Set(field, Redacted)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	return []byte(values.Encode())
+}
+
+// redactJSON redacts the string values of secret fields at any depth, such as
+// the access token in the metadata of the Nest API root.
+func redactJSON(body []byte) []byte {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil || d.More() {
+		return body
+	}
+	if !redactValue(v) {
+		return body
+	}
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// redactValue replaces secret string fields in a decoded JSON value and
+// reports whether any were replaced.
+func redactValue(v interface{}) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range v {
+			if _, ok := fv.(string); ok && isSecretField(k) {
+				v[k] = Redacted
+				redacted = true
+				continue
+			}
+			if redactValue(fv) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, ev := range v {
+			if redactValue(ev) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func isSecretField(name string) bool {
+	for _, field := range secretFields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// redactEventLine redacts the payload of a data line of an event stream.
+// Other lines are returned unchanged.
+func redactEventLine(line []byte) []byte {
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return line
+	}
+	content := bytes.TrimRight(line, "\r\n")
+	ending := line[len(content):]
+	prefix := []byte("data:")
+	payload := content[len(prefix):]
+	if bytes.HasPrefix(payload, []byte(" ")) {
+		prefix = []byte("data: ")
+		payload = payload[1:]
+	}
+	redacted := redactJSON(payload)
+	if bytes.Equal(redacted, payload) {
+		return line
+	}
+	out := append(append([]byte(nil), prefix...), redacted...)
+	return append(out, ending...)
+}
+
+// redactEvents redacts the data lines of part of an event stream.
+func redactEvents(data []byte) []byte {
+	var out []byte
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		out = append(out, redactEventLine(data[:i])...)
+		data = data[i:]
+	}
+	return out
+}
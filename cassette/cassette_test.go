@@ -0,0 +1,95 @@
+package cassette
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Body(t *testing.T) {
+	tests := []struct {
+		body Body
+		json string
+	}{
+		{Body(`{"hvac_mode":"heat"}`), `"{\"hvac_mode\":\"heat\"}"`},
+		{Body{0xff, 0xd8, 0xff}, `{"base64":"/9j/"}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.body)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.json, string(b))
+
+		var decoded Body
+		assert.NoError(t, json.Unmarshal(b, &decoded))
+		assert.Equal(t, tt.body, decoded)
+	}
+}
+
+func Test_Redact(t *testing.T) {
+	assert.Equal(t, "https://developer-api.nest.com/devices?auth=REDACTED", redactURL("https://developer-api.nest.com/devices?auth=c.token"))
+	assert.Equal(t, "https://developer-api.nest.com/devices", redactURL("https://developer-api.nest.com/devices"))
+
+	h := redactHeader(http.Header{
+		"Authorization": {"Bearer c.token"},
+		"Location":      {"https://firebase-apiserver.example.com/?auth=c.token"},
+		"Accept":        {"text/event-stream"},
+	})
+	assert.Equal(t, http.Header{
+		"Authorization": {"REDACTED"},
+		"Location":      {"https://firebase-apiserver.example.com/?auth=REDACTED"},
+		"Accept":        {"text/event-stream"},
+	}, h)
+}
+
+func Test_RedactBody(t *testing.T) {
+	form := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		want   string
+	}{
+		{"token request", form, "client_id=id&client_secret=s3cret&code=code123&grant_type=authorization_code",
+			"client_id=id&client_secret=REDACTED&code=REDACTED&grant_type=authorization_code"},
+		{"token response", nil, `{"access_token":"c.token","expires_in":315360000,"refresh_token":"r.token"}`,
+			`{"access_token":"REDACTED","expires_in":315360000,"refresh_token":"REDACTED"}`},
+		{"nested token", nil, `{"devices":{},"metadata":{"access_token":"c.token","client_version":1}}`,
+			`{"devices":{},"metadata":{"access_token":"REDACTED","client_version":1}}`},
+		{"token in array", nil, `[{"refresh_token":"r.token"}]`, `[{"refresh_token":"REDACTED"}]`},
+		{"numeric code", nil, `{"error":{"code":400,"message":"Heat value out of range."}}`,
+			`{"error":{"code":400,"message":"Heat value out of range."}}`},
+		{"json without credentials", nil, `{"hvac_mode": "heat"}`, `{"hvac_mode": "heat"}`},
+		{"not json", nil, "jpeg", "jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(redactBody(tt.header, []byte(tt.body))))
+		})
+	}
+}
+
+func Test_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "devices.json")
+
+	c := &Cassette{Interactions: []*Interaction{{
+		Request:  Request{Method: http.MethodGet, URL: "https://developer-api.nest.com/"},
+		Response: Response{StatusCode: http.StatusOK, Chunks: []Chunk{{Delay: 5, Data: Body("event: keep-alive\n")}}},
+	}}}
+	assert.NoError(t, c.Save(path))
+	loaded, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, c, loaded)
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recorder is an http.RoundTripper that records interactions made through
+// Transport. Event streams are recorded as they are read, chunk by chunk.
+type Recorder struct {
+	// Transport makes the requests; http.DefaultTransport is used when nil.
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	now      func() time.Time
+}
+
+// NewRecorder creates a recorder using transport, which may be an OAuth2
+// transport so recorded requests are authorized.
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport, now: time.Now}
+}
+
+// Client returns an HTTP client that records through r.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    redactURL(req.URL.String()),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(req.Header, reqBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	r.mu.Unlock()
+
+	if isStream(resp.Header) {
+		resp.Body = &recordingBody{ReadCloser: resp.Body, r: r, in: in, last: r.now()}
+		return resp, nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	in.Response.Body = redactBody(resp.Header, b)
+	r.mu.Unlock()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return resp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far. Chunks of
+// streams that are still open are included up to the last read.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &Cassette{}
+	for _, in := range r.cassette.Interactions {
+		cp := *in
+		cp.Response.Chunks = append([]Chunk(nil), in.Response.Chunks...)
+		c.Interactions = append(c.Interactions, &cp)
+	}
+	return c
+}
+
+// Save writes the interactions recorded so far to a file.
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// recordingBody records the chunks read from a streamed response body. Data is
+// recorded up to the last complete line read, so the data lines of events can
+// be redacted; the rest is recorded with the next chunk or when the body ends.
+type recordingBody struct {
+	io.ReadCloser
+	r       *Recorder
+	in      *Interaction
+	last    time.Time
+	pending []byte
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.pending = append(b.pending, p[:n]...)
+	if err != nil {
+		b.flush(len(b.pending))
+	} else if i := bytes.LastIndexByte(b.pending, '\n'); i >= 0 {
+		b.flush(i + 1)
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.flush(len(b.pending))
+	return b.ReadCloser.Close()
+}
+
+// flush records the first n pending bytes as a chunk.
+func (b *recordingBody) flush(n int) {
+	if n == 0 {
+		return
+	}
+	now := b.r.now()
+	b.r.mu.Lock()
+	b.in.Response.Chunks = append(b.in.Response.Chunks, Chunk{
+		Delay: now.Sub(b.last),
+		Data:  redactEvents(b.pending[:n]),
+	})
+	b.r.mu.Unlock()
+	b.pending = append(b.pending[:0], b.pending[n:]...)
+	b.last = now
+}
+
+func isStream(h http.Header) bool {
+	return strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+}
@@ -0,0 +1,161 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/config"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/nesttest"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// authTransport adds an Authorization header like an OAuth2 transport.
+type authTransport struct {
+	base http.RoundTripper
+}
+
+func (t authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer c.token")
+	return t.base.RoundTrip(req)
+}
+
+func newTestServer() *nesttest.Server {
+	s := nesttest.NewServer()
+	s.AddThermostat(&device.Thermostat{DeviceID: "t1", HvacMode: device.HvacModeHeat, CanHeat: true, TargetTemperatureF: 68})
+	return s
+}
+
+// record records a session with s: a device list, a target temperature change
+// and a thermostat stream receiving the change.
+func record(t *testing.T, s *nesttest.Server) *Recorder {
+	rec := NewRecorder(s.Client().Transport)
+	rec.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+
+	client := &http.Client{Transport: authTransport{rec}}
+	n, _ := nest.NewClient(config.Config{APIURL: s.URL}, client)
+	stream, _ := n.Thermostats.Stream("t1")
+	events, err := stream.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+
+	_, err = n.Devices()
+	assert.NoError(t, err)
+	assert.NoError(t, n.Thermostats.SetTargetTemperature("t1", device.Fahrenheit, 70))
+	<-events
+	stream.Close()
+	return rec
+}
+
+func Test_Recorder(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	c := record(t, s).Cassette()
+	if !assert.Len(t, c.Interactions, 3) {
+		return
+	}
+
+	streamed := c.Interactions[0]
+	assert.Equal(t, s.URL+"/devices/thermostats/t1", streamed.Request.URL)
+	assert.Equal(t, "REDACTED", streamed.Request.Header.Get("Authorization"))
+	assert.Empty(t, streamed.Response.Body)
+	assert.NotEmpty(t, streamed.Response.Chunks)
+
+	put := c.Interactions[2]
+	assert.Equal(t, http.MethodPut, put.Request.Method)
+	assert.JSONEq(t, `{"target_temperature_f": 70}`, string(put.Request.Body))
+	assert.JSONEq(t, `{"target_temperature_f": 70}`, string(put.Response.Body))
+}
+
+func Test_RecordTokenExchange(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"c.token","token_type":"Bearer","refresh_token":"r.token","expires_in":3600}`)
+	}))
+	defer ts.Close()
+	conf := &oauth2.Config{
+		ClientID:     "id",
+		ClientSecret: "s3cret",
+		Endpoint:     oauth2.Endpoint{TokenURL: ts.URL, AuthStyle: oauth2.AuthStyleInParams},
+	}
+
+	rec := NewRecorder(ts.Client().Transport)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, rec.Client())
+	tok, err := conf.Exchange(ctx, "code123")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "c.token", tok.AccessToken, "the client should receive the real token")
+	}
+	b, err := json.Marshal(rec.Cassette())
+	assert.NoError(t, err)
+	for _, secret := range []string{"c.token", "r.token", "s3cret", "code123"} {
+		assert.NotContains(t, string(b), secret)
+	}
+
+	// the redacted exchange still replays
+	ctx = context.WithValue(context.Background(), oauth2.HTTPClient, NewReplayer(rec.Cassette()).Client())
+	tok, err = conf.Exchange(ctx, "code123")
+	if assert.NoError(t, err) {
+		assert.Equal(t, Redacted, tok.AccessToken)
+	}
+}
+
+func Test_RecordRootToken(t *testing.T) {
+	const root = `{"devices":{},"metadata":{"access_token":"c.token","client_version":1}}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, root)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		// split the data line of the event across writes
+		event := "event: put\ndata: {\"path\":\"/\",\"data\":" + root + "}\n\n"
+		for _, part := range []string{event[:30], event[30:]} {
+			fmt.Fprint(w, part)
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	rec := NewRecorder(ts.Client().Transport)
+	n, _ := nest.NewClient(config.Config{APIURL: ts.URL}, rec.Client())
+	_, err := n.Devices()
+	assert.NoError(t, err)
+
+	stream, _ := nest.NewStream(&config.Config{APIURL: ts.URL}, rec.Client())
+	events, err := stream.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := <-events
+	assert.Contains(t, string(e.Data()), "c.token", "the client should receive the real token")
+	for range events {
+	}
+
+	c := rec.Cassette()
+	if !assert.Len(t, c.Interactions, 2) {
+		return
+	}
+	assert.JSONEq(t, `{"devices":{},"metadata":{"access_token":"REDACTED","client_version":1}}`, string(c.Interactions[0].Response.Body))
+	var streamed []byte
+	for _, chunk := range c.Interactions[1].Response.Chunks {
+		streamed = append(streamed, chunk.Data...)
+	}
+	assert.Equal(t, "event: put\ndata: {\"data\":{\"devices\":{},\"metadata\":{\"access_token\":\"REDACTED\",\"client_version\":1}},\"path\":\"/\"}\n\n", string(streamed))
+
+	b, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "c.token")
+}
@@ -0,0 +1,132 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Replayer is an http.RoundTripper that answers requests from a cassette.
+// Each request is matched, by method, redacted URL and redacted body, to the
+// first interaction not yet replayed. Streamed responses are replayed chunk by
+// chunk; Speed scales their recorded delays, so 1 replays a stream at the pace
+// it was recorded and the default of 0 replays it without delays.
+type Replayer struct {
+	Speed float64
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer creates a replayer for a cassette.
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{cassette: c, used: make([]bool, len(c.Interactions))}
+}
+
+// Open loads a cassette from a file and creates a replayer for it.
+func Open(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(c), nil
+}
+
+// Client returns an HTTP client that replays through r. It can be passed to
+// nest.NewClient and nest.NewStream.
+func (r *Replayer) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Done reports whether every interaction has been replayed.
+func (r *Replayer) Done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, used := range r.used {
+		if !used {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+	in, err := r.match(req.Method, redactURL(req.URL.String()), redactBody(req.Header, body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode: in.Response.StatusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    req,
+	}
+	for k, v := range in.Response.Header {
+		resp.Header[k] = append([]string(nil), v...)
+	}
+	if len(in.Response.Chunks) == 0 {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(in.Response.Body))
+		resp.ContentLength = int64(len(in.Response.Body))
+		return resp, nil
+	}
+
+	pr, pw := io.Pipe()
+	go r.stream(req, pw, in.Response.Chunks)
+	resp.Body = pr
+	resp.ContentLength = -1
+	return resp, nil
+}
+
+func (r *Replayer) match(method, url string, body []byte) (*Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if r.used[i] || in.Request.Method != method || in.Request.URL != url || !bytes.Equal(in.Request.Body, body) {
+			continue
+		}
+		r.used[i] = true
+		return in, nil
+	}
+	return nil, fmt.Errorf("cassette: no interaction recorded for %s %s", method, url)
+}
+
+// stream writes the chunks of a streamed response with their recorded delays,
+// stopping early when the request is canceled.
+func (r *Replayer) stream(req *http.Request, pw *io.PipeWriter, chunks []Chunk) {
+	ctx := req.Context()
+	for _, c := range chunks {
+		if d := time.Duration(float64(c.Delay) * r.Speed); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				pw.CloseWithError(ctx.Err())
+				return
+			}
+		}
+		if _, err := pw.Write(c.Data); err != nil {
+			return
+		}
+	}
+	pw.Close()
+}
@@ -0,0 +1,81 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/config"
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Replayer(t *testing.T) {
+	s := newTestServer()
+	rec := record(t, s)
+	s.Close()
+
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.json")
+	assert.NoError(t, rec.Save(path))
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := nest.NewClient(config.Config{APIURL: s.URL}, r.Client())
+
+	stream, _ := nest.NewStream(&config.Config{APIURL: s.URL + "/devices/thermostats/t1"}, r.Client())
+	events, err := stream.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targets []int
+	for e := range events {
+		_, _, data, err := e.GetEvent()
+		assert.NoError(t, err)
+		targets = append(targets, data.(*device.Thermostat).TargetTemperatureF)
+	}
+	assert.Equal(t, []int{68, 70}, targets, "the stream should replay the recorded events")
+
+	devices, err := n.Devices()
+	assert.NoError(t, err)
+	assert.Equal(t, 68, devices.Thermostats["t1"].TargetTemperatureF)
+	assert.NoError(t, n.Thermostats.SetTargetTemperature("t1", device.Fahrenheit, 70))
+	assert.True(t, r.Done())
+
+	err = n.Thermostats.SetTargetTemperature("t1", device.Fahrenheit, 72)
+	assert.EqualError(t, err, `Put "`+s.URL+`/devices/thermostats/t1": cassette: no interaction recorded for PUT `+s.URL+`/devices/thermostats/t1`)
+}
+
+func Test_ReplayerTiming(t *testing.T) {
+	c := &Cassette{Interactions: []*Interaction{{
+		Request: Request{Method: "GET", URL: "http://nest.test/"},
+		Response: Response{StatusCode: 200, Chunks: []Chunk{
+			{Delay: 0, Data: Body("event: keep-alive\ndata: null\n")},
+			{Delay: time.Second, Data: Body("event: keep-alive\ndata: null\n")},
+		}},
+	}}}
+	r := NewReplayer(c)
+	r.Speed = 0.05
+
+	stream, _ := nest.NewStream(&config.Config{APIURL: "http://nest.test/"}, r.Client())
+	events, err := stream.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	n := 0
+	for range events {
+		n++
+	}
+	assert.Equal(t, 2, n)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond, "delays should be scaled by Speed")
+}
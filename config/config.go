@@ -9,9 +9,37 @@ const APIURL = "https://developer-api.nest.com"
 // AuthURL for generating authorization request
 const AuthURL = "https://home.nest.com/login/oauth2"
 
+// RevokeURL provides the default endpoint for revoking access tokens.
+const RevokeURL = "https://api.home.nest.com/oauth2/access_tokens"
+
 // Config represents configuration for Nest authentication API
 type Config struct {
 	ClientID string
 	Secret   string
 	APIURL   string
+
+	// TokenURL is the OAuth2 token endpoint. Defaults to TokenURL.
+	TokenURL string
+	// AuthURL is the OAuth2 authorization endpoint. Defaults to AuthURL.
+	AuthURL string
+	// RevokeURL is the endpoint used to revoke access tokens. Defaults to RevokeURL.
+	RevokeURL string
+	// Scopes are the OAuth2 scopes requested. Nest grants the permissions set on
+	// the product, so none are requested by default.
+	Scopes []string
+}
+
+// WithDefaults returns a copy of the configuration with empty auth endpoints
+// set to their defaults.
+func (c Config) WithDefaults() Config {
+	if c.TokenURL == "" {
+		c.TokenURL = TokenURL
+	}
+	if c.AuthURL == "" {
+		c.AuthURL = AuthURL
+	}
+	if c.RevokeURL == "" {
+		c.RevokeURL = RevokeURL
+	}
+	return c
 }
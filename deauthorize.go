@@ -0,0 +1,71 @@
+package nest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jtsiros/nest/auth"
+	"github.com/jtsiros/nest/config"
+	"golang.org/x/oauth2"
+)
+
+// Deauthorize closes every open stream created through the client and revokes the
+// access token, so the application loses access to the user's devices. The
+// client's HTTP client is used for the revocation unless ctx carries one.
+// See https://developers.nest.com/guides/api/how-to-auth#deauthorization
+//
+func (nest *Client) Deauthorize(ctx context.Context, token string) error {
+	nest.CloseStreams()
+
+	if _, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); !ok && nest.httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, nest.httpClient)
+	}
+	revokeURL := nest.revokeURL
+	if revokeURL == "" {
+		revokeURL = config.RevokeURL
+	}
+	return auth.RevokeAt(ctx, revokeURL, token)
+}
+
+// CloseStreams closes every stream created through the client that has open
+// connections.
+func (nest *Client) CloseStreams() {
+	nest.mu.Lock()
+	streams := make([]*Stream, 0, len(nest.streams))
+	for s := range nest.streams {
+		streams = append(streams, s)
+	}
+	nest.mu.Unlock()
+
+	for _, s := range streams {
+		s.Close()
+	}
+}
+
+// trackStream registers a stream created by a service so it can be closed by
+// CloseStreams while it has open connections. Streams are forgotten once
+// their connections end or they are closed, so streams that are dropped
+// without being closed are not kept alive by the client.
+func (nest *Client) trackStream(s *Stream, err error) (*Stream, error) {
+	if err != nil {
+		return nil, err
+	}
+	s.onOpen = nest.addStream
+	s.onClose = nest.untrackStream
+	return s, nil
+}
+
+func (nest *Client) addStream(s *Stream) {
+	nest.mu.Lock()
+	if nest.streams == nil {
+		nest.streams = map[*Stream]struct{}{}
+	}
+	nest.streams[s] = struct{}{}
+	nest.mu.Unlock()
+}
+
+func (nest *Client) untrackStream(s *Stream) {
+	nest.mu.Lock()
+	delete(nest.streams, s)
+	nest.mu.Unlock()
+}
@@ -0,0 +1,133 @@
+package nest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Deauthorize(t *testing.T) {
+	revoked := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			revoked <- r.URL.Path
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		// keep the stream open until the client goes away
+		fmt.Fprint(w, "event: keep-alive\ndata: null\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	n, err := NewClient(config.Config{APIURL: ts.URL, RevokeURL: ts.URL + "/oauth2/access_tokens"}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := n.Thermostats.Stream("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-events
+
+	assert.NoError(t, n.Deauthorize(context.Background(), "c.token"))
+	assert.Equal(t, "/oauth2/access_tokens/c.token", <-revoked)
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok, "stream should be closed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed")
+	}
+	_, err = s.Open()
+	assert.Equal(t, ErrStreamClosed, err)
+	assert.Empty(t, n.streams)
+}
+
+func Test_TrackStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: keep-alive\ndata: null\n")
+	}))
+	defer ts.Close()
+
+	n, err := NewClient(config.Config{APIURL: ts.URL}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := n.Cameras.Stream("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, n.streams, "streams should not be tracked before they are opened")
+
+	events, err := s.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range events {
+	}
+	// the stream is untracked after its events channel is closed
+	for i := 0; i < 100; i++ {
+		n.mu.Lock()
+		tracked := len(n.streams)
+		n.mu.Unlock()
+		if tracked == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	n.mu.Lock()
+	assert.Empty(t, n.streams, "streams should be untracked when their connections end")
+	n.mu.Unlock()
+}
+
+func Test_CloseStreamsWhileConnecting(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// never respond, so the stream stays connecting
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	n, err := NewClient(config.Config{APIURL: ts.URL}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := n.Thermostats.Stream("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Open()
+		errs <- err
+	}()
+	for i := 0; i < 100; i++ {
+		n.mu.Lock()
+		tracked := len(n.streams)
+		n.mu.Unlock()
+		if tracked == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	n.CloseStreams()
+	select {
+	case err := <-errs:
+		assert.Equal(t, ErrStreamClosed, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connecting stream was not closed")
+	}
+	assert.Empty(t, n.streams)
+}
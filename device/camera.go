@@ -1,6 +1,9 @@
 package device
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 // Camera is a Nest Camera object. This is intended to work with all Nest Cam models
 // and shows all Nest Cam data
@@ -26,6 +29,30 @@ type Camera struct {
 	ActivityZones         []*ActivityZone `json:"activity_zones,omitempty"`
 	PublicShareURL        string          `json:"public_share_url,omitempty"`
 	SnapshotURL           string          `json:"snapshot_url,omitempty"`
+	LastEvent             *CameraEvent    `json:"last_event,omitempty"`
+}
+
+// CameraEvent is the most recent sound, motion or person event recorded by a camera.
+// https://developers.nest.com/reference/api-camera#last_event
+//
+type CameraEvent struct {
+	HasSound         bool      `json:"has_sound,omitempty"`
+	HasMotion        bool      `json:"has_motion,omitempty"`
+	HasPerson        bool      `json:"has_person,omitempty"`
+	StartTime        time.Time `json:"start_time,omitempty"`
+	EndTime          time.Time `json:"end_time,omitempty"`
+	UrlsExpireTime   time.Time `json:"urls_expire_time,omitempty"`
+	WebURL           string    `json:"web_url,omitempty"`
+	AppURL           string    `json:"app_url,omitempty"`
+	ImageURL         string    `json:"image_url,omitempty"`
+	AnimatedImageURL string    `json:"animated_image_url,omitempty"`
+	ActivityZoneIDs  []string  `json:"activity_zone_ids,omitempty"`
+}
+
+// InProgress reports whether the event has not ended yet. The Nest API reports
+// an end time earlier than the start time while an event is ongoing.
+func (e *CameraEvent) InProgress() bool {
+	return e.EndTime.IsZero() || e.EndTime.Before(e.StartTime)
 }
 
 //ActivityZone represents an Activity Zone.
@@ -33,3 +60,34 @@ type ActivityZone struct {
 	Name string `json:"name,omitempty"`
 	ID   int    `json:"id,omitempty"`
 }
+
+// ActivityZone returns the activity zone with the given id as reported in
+// last_event.activity_zone_ids.
+func (c *Camera) ActivityZone(id string) (*ActivityZone, bool) {
+	for _, z := range c.ActivityZones {
+		if z != nil && strconv.Itoa(z.ID) == id {
+			return z, true
+		}
+	}
+	return nil, false
+}
+
+// ZoneNames resolves activity zone ids to zone names. Ids that do not match
+// an activity zone of the camera are skipped.
+func (c *Camera) ZoneNames(ids []string) []string {
+	var names []string
+	for _, id := range ids {
+		if z, ok := c.ActivityZone(id); ok {
+			names = append(names, z.Name)
+		}
+	}
+	return names
+}
+
+// EventZoneNames returns the names of the activity zones of the last event.
+func (c *Camera) EventZoneNames() []string {
+	if c.LastEvent == nil {
+		return nil
+	}
+	return c.ZoneNames(c.LastEvent.ActivityZoneIDs)
+}
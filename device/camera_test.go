@@ -0,0 +1,75 @@
+package device
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UnmarshalCameraLastEvent(t *testing.T) {
+	body := `{
+		"device_id": "123",
+		"last_event": {
+			"has_sound": false,
+			"has_motion": true,
+			"has_person": true,
+			"start_time": "2016-12-29T00:00:00.000Z",
+			"end_time": "2016-12-29T18:42:00.000Z",
+			"urls_expire_time": "2016-12-29T18:42:00.000Z",
+			"web_url": "https://home.nest.com/cameras/device_id?auth=access_token",
+			"app_url": "nestmobile://cameras/device_id?auth=access_token",
+			"image_url": "https://domain/sample_image_url",
+			"animated_image_url": "https://domain/sample_animated_image_url",
+			"activity_zone_ids": ["244083"]
+		}
+	}`
+
+	var c Camera
+	assert.NoError(t, json.Unmarshal([]byte(body), &c))
+	if assert.NotNil(t, c.LastEvent) {
+		assert.True(t, c.LastEvent.HasMotion)
+		assert.True(t, c.LastEvent.HasPerson)
+		assert.False(t, c.LastEvent.HasSound)
+		assert.Equal(t, time.Date(2016, 12, 29, 18, 42, 0, 0, time.UTC), c.LastEvent.EndTime)
+		assert.Equal(t, "https://domain/sample_animated_image_url", c.LastEvent.AnimatedImageURL)
+		assert.Equal(t, []string{"244083"}, c.LastEvent.ActivityZoneIDs)
+		assert.False(t, c.LastEvent.InProgress())
+	}
+}
+
+func Test_CameraEventInProgress(t *testing.T) {
+	start := time.Date(2016, 12, 29, 18, 0, 0, 0, time.UTC)
+	tt := []struct {
+		end      time.Time
+		expected bool
+	}{
+		{time.Time{}, true},
+		{start.Add(-time.Hour), true},
+		{start.Add(time.Minute), false},
+	}
+
+	for _, tc := range tt {
+		e := CameraEvent{StartTime: start, EndTime: tc.end}
+		assert.Equal(t, tc.expected, e.InProgress())
+	}
+}
+
+func Test_ZoneNames(t *testing.T) {
+	c := Camera{
+		ActivityZones: []*ActivityZone{{Name: "Driveway", ID: 244083}, {Name: "Porch", ID: 244084}},
+		LastEvent:     &CameraEvent{ActivityZoneIDs: []string{"244084", "1"}},
+	}
+
+	assert.Equal(t, []string{"Driveway", "Porch"}, c.ZoneNames([]string{"244083", "244084"}))
+	assert.Equal(t, []string{"Porch"}, c.EventZoneNames(), "unknown zone ids should be skipped")
+
+	z, ok := c.ActivityZone("244083")
+	assert.True(t, ok)
+	assert.Equal(t, "Driveway", z.Name)
+
+	_, ok = c.ActivityZone("1")
+	assert.False(t, ok)
+	assert.Nil(t, (&Camera{}).EventZoneNames())
+}
@@ -0,0 +1,252 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// HvacMode indicates the HVAC system heating/cooling mode of a Thermostat.
+// See https://developers.nest.com/reference/api-thermostat#hvac_mode
+type HvacMode string
+
+// Supported HVAC modes
+const (
+	HvacModeHeat     HvacMode = "heat"
+	HvacModeCool     HvacMode = "cool"
+	HvacModeHeatCool HvacMode = "heat-cool"
+	HvacModeEco      HvacMode = "eco"
+	HvacModeOff      HvacMode = "off"
+)
+
+// HvacState indicates whether the HVAC system is actively heating, cooling or is off.
+// See https://developers.nest.com/reference/api-thermostat#hvac_state
+type HvacState string
+
+// Supported HVAC states
+const (
+	HvacStateHeating HvacState = "heating"
+	HvacStateCooling HvacState = "cooling"
+	HvacStateOff     HvacState = "off"
+)
+
+// TemperatureScale is the temperature scale used for display on a Thermostat.
+// See https://developers.nest.com/reference/api-thermostat#temperature_scale
+type TemperatureScale string
+
+// Supported temperature scales
+const (
+	Fahrenheit TemperatureScale = "F"
+	Celsius    TemperatureScale = "C"
+)
+
+// BatteryHealth indicates the battery state of a Smoke+CO Alarm.
+// See https://developers.nest.com/reference/api-smoke-co-alarm#battery_health
+type BatteryHealth string
+
+// Supported battery health values
+const (
+	BatteryHealthOK      BatteryHealth = "ok"
+	BatteryHealthReplace BatteryHealth = "replace"
+)
+
+// AlarmState indicates the smoke or CO alarm status of a Smoke+CO Alarm.
+// See https://developers.nest.com/reference/api-smoke-co-alarm#co_alarm_state
+type AlarmState string
+
+// Supported alarm states
+const (
+	AlarmStateOK        AlarmState = "ok"
+	AlarmStateWarning   AlarmState = "warning"
+	AlarmStateEmergency AlarmState = "emergency"
+)
+
+// UIColorState indicates the color of the Smoke+CO Alarm light ring.
+// See https://developers.nest.com/reference/api-smoke-co-alarm#ui_color_state
+type UIColorState string
+
+// Supported UI color states
+const (
+	UIColorGray   UIColorState = "gray"
+	UIColorGreen  UIColorState = "green"
+	UIColorYellow UIColorState = "yellow"
+	UIColorRed    UIColorState = "red"
+)
+
+// InvalidValueError is returned when a value is not one of the values documented
+// by the Nest API for an enumerated field.
+type InvalidValueError struct {
+	Kind  string
+	Value string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid %s: %q", e.Kind, e.Value)
+}
+
+func (m HvacMode) String() string { return string(m) }
+
+// Validate returns an error if m is not a supported HVAC mode.
+func (m HvacMode) Validate() error {
+	switch m {
+	case HvacModeHeat, HvacModeCool, HvacModeHeatCool, HvacModeEco, HvacModeOff:
+		return nil
+	}
+	return &InvalidValueError{Kind: "hvac mode", Value: string(m)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *HvacMode) UnmarshalJSON(b []byte) error {
+	s, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*m = HvacMode(s)
+	return nil
+}
+
+func (s HvacState) String() string { return string(s) }
+
+// Validate returns an error if s is not a supported HVAC state.
+func (s HvacState) Validate() error {
+	switch s {
+	case HvacStateHeating, HvacStateCooling, HvacStateOff:
+		return nil
+	}
+	return &InvalidValueError{Kind: "hvac state", Value: string(s)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *HvacState) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*s = HvacState(v)
+	return nil
+}
+
+func (t TemperatureScale) String() string { return string(t) }
+
+// Validate returns an error if t is not a supported temperature scale.
+func (t TemperatureScale) Validate() error {
+	switch t {
+	case Fahrenheit, Celsius:
+		return nil
+	}
+	return &InvalidValueError{Kind: "temperature scale", Value: string(t)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *TemperatureScale) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*t = TemperatureScale(v)
+	return nil
+}
+
+func (h BatteryHealth) String() string { return string(h) }
+
+// Validate returns an error if h is not a supported battery health value.
+func (h BatteryHealth) Validate() error {
+	switch h {
+	case BatteryHealthOK, BatteryHealthReplace:
+		return nil
+	}
+	return &InvalidValueError{Kind: "battery health", Value: string(h)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *BatteryHealth) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*h = BatteryHealth(v)
+	return nil
+}
+
+func (a AlarmState) String() string { return string(a) }
+
+// Validate returns an error if a is not a supported alarm state.
+func (a AlarmState) Validate() error {
+	switch a {
+	case AlarmStateOK, AlarmStateWarning, AlarmStateEmergency:
+		return nil
+	}
+	return &InvalidValueError{Kind: "alarm state", Value: string(a)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *AlarmState) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*a = AlarmState(v)
+	return nil
+}
+
+func (c UIColorState) String() string { return string(c) }
+
+// Validate returns an error if c is not a supported UI color state.
+func (c UIColorState) Validate() error {
+	switch c {
+	case UIColorGray, UIColorGreen, UIColorYellow, UIColorRed:
+		return nil
+	}
+	return &InvalidValueError{Kind: "ui color state", Value: string(c)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *UIColorState) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*c = UIColorState(v)
+	return nil
+}
+
+// AwayState indicates whether a Structure is occupied.
+// See https://developers.nest.com/reference/api-structure#away
+type AwayState string
+
+// Supported away states
+const (
+	AwayStateHome AwayState = "home"
+	AwayStateAway AwayState = "away"
+)
+
+func (a AwayState) String() string { return string(a) }
+
+// Validate returns an error if a is not a supported away state.
+func (a AwayState) Validate() error {
+	switch a {
+	case AwayStateHome, AwayStateAway:
+		return nil
+	}
+	return &InvalidValueError{Kind: "away state", Value: string(a)}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *AwayState) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b)
+	if err != nil {
+		return err
+	}
+	*a = AwayState(v)
+	return nil
+}
+
+// unmarshalEnum decodes a JSON string. Values are not validated, so a value added to
+// the Nest API does not fail decoding the whole device, and the value is encoded again
+// unchanged; Validate checks values before they are written.
+func unmarshalEnum(b []byte) (string, error) {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "", err
+	}
+	return v, nil
+}
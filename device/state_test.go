@@ -0,0 +1,103 @@
+package device
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UnmarshalThermostatStates(t *testing.T) {
+	tt := []struct {
+		body      string
+		mode      HvacMode
+		previous  HvacMode
+		state     HvacState
+		scale     TemperatureScale
+		expectErr bool
+	}{
+		{`{"hvac_mode":"heat","previous_hvac_mode":"","hvac_state":"heating","temperature_scale":"F"}`, HvacModeHeat, "", HvacStateHeating, Fahrenheit, false},
+		{`{"hvac_mode":"eco","previous_hvac_mode":"heat-cool","hvac_state":"off","temperature_scale":"C"}`, HvacModeEco, HvacModeHeatCool, HvacStateOff, Celsius, false},
+		{`{"hvac_mode":"blah"}`, HvacMode("blah"), "", "", "", false},
+		{`{"hvac_state":"idle"}`, "", "", HvacState("idle"), "", false},
+		{`{"temperature_scale":"K"}`, "", "", "", TemperatureScale("K"), false},
+		{`{"hvac_mode":5}`, "", "", "", "", true},
+	}
+
+	for _, tc := range tt {
+		var th Thermostat
+		err := json.Unmarshal([]byte(tc.body), &th)
+		if tc.expectErr {
+			assert.Error(t, err, tc.body)
+			continue
+		}
+		assert.NoError(t, err, tc.body)
+		assert.Equal(t, tc.mode, th.HvacMode)
+		assert.Equal(t, tc.previous, th.PreviousHvacMode)
+		assert.Equal(t, tc.state, th.HvacState)
+		assert.Equal(t, tc.scale, th.TemperatureScale)
+	}
+}
+
+func Test_UnmarshalSmokeAlarmStates(t *testing.T) {
+	body := `{"battery_health":"replace","co_alarm_state":"warning","smoke_alarm_state":"emergency","ui_color_state":"red"}`
+
+	var sa SmokeAlarm
+	assert.NoError(t, json.Unmarshal([]byte(body), &sa))
+	assert.Equal(t, BatteryHealthReplace, sa.BatteryHealth)
+	assert.Equal(t, AlarmStateWarning, sa.CoAlarmState)
+	assert.Equal(t, AlarmStateEmergency, sa.SmokeAlarmState)
+	assert.Equal(t, UIColorRed, sa.UIColorState)
+
+	// unknown values are decoded, but do not validate
+	assert.NoError(t, json.Unmarshal([]byte(`{"battery_health":"low","co_alarm_state":"panic","ui_color_state":"blue"}`), &sa))
+	assert.Error(t, sa.BatteryHealth.Validate())
+	assert.Error(t, sa.CoAlarmState.Validate())
+	assert.Error(t, sa.UIColorState.Validate())
+	assert.Error(t, json.Unmarshal([]byte(`{"co_alarm_state":1}`), &sa))
+}
+
+func Test_MarshalStates(t *testing.T) {
+	b, err := json.Marshal(struct {
+		Mode     HvacMode  `json:"hvac_mode"`
+		Previous HvacMode  `json:"previous_hvac_mode,omitempty"`
+		State    HvacState `json:"hvac_state"`
+	}{Mode: HvacModeHeatCool, State: HvacStateCooling})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"hvac_mode":"heat-cool","hvac_state":"cooling"}`, string(b))
+
+	// values unknown to the library round-trip unchanged
+	var th Thermostat
+	assert.NoError(t, json.Unmarshal([]byte(`{"hvac_mode":"boost"}`), &th))
+	b, err = json.Marshal(th)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"hvac_mode":"boost"`)
+
+	b, err = json.Marshal(SmokeAlarm{BatteryHealth: BatteryHealth("low")})
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"battery_health":"low"`)
+}
+
+func Test_Validate(t *testing.T) {
+	tt := []struct {
+		err      error
+		expected string
+	}{
+		{HvacModeOff.Validate(), ""},
+		{HvacMode("").Validate(), `invalid hvac mode: ""`},
+		{HvacState("idle").Validate(), `invalid hvac state: "idle"`},
+		{TemperatureScale("K").Validate(), `invalid temperature scale: "K"`},
+		{BatteryHealth("low").Validate(), `invalid battery health: "low"`},
+		{AlarmState("panic").Validate(), `invalid alarm state: "panic"`},
+		{UIColorState("blue").Validate(), `invalid ui color state: "blue"`},
+	}
+
+	for _, tc := range tt {
+		if tc.expected == "" {
+			assert.NoError(t, tc.err)
+			continue
+		}
+		assert.EqualError(t, tc.err, tc.expected)
+	}
+	assert.Equal(t, "heat-cool", HvacModeHeatCool.String())
+}
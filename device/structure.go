@@ -0,0 +1,34 @@
+package device
+
+import "time"
+
+// Structure represents a home or building containing Nest devices. Structures
+// carry the occupancy (away) state and time zone shared by their devices.
+// https://developers.nest.com/reference/api-structure
+//
+type Structure struct {
+	StructureID     string            `json:"structure_id,omitempty"`
+	Name            string            `json:"name,omitempty"`
+	CountryCode     string            `json:"country_code,omitempty"`
+	PostalCode      string            `json:"postal_code,omitempty"`
+	TimeZone        string            `json:"time_zone,omitempty"`
+	Away            AwayState         `json:"away,omitempty"`
+	Thermostats     []string          `json:"thermostats,omitempty"`
+	SmokeCoAlarms   []string          `json:"smoke_co_alarms,omitempty"`
+	Cameras         []string          `json:"cameras,omitempty"`
+	CoAlarmState    AlarmState        `json:"co_alarm_state,omitempty"`
+	SmokeAlarmState AlarmState        `json:"smoke_alarm_state,omitempty"`
+	Wheres          map[string]*Where `json:"wheres,omitempty"`
+}
+
+// Where represents a named location within a structure, e.g. "Living Room".
+type Where struct {
+	WhereID string `json:"where_id,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+// Location returns the time zone of the structure. UTC is returned when the
+// structure has no time zone set.
+func (s *Structure) Location() (*time.Location, error) {
+	return time.LoadLocation(s.TimeZone)
+}
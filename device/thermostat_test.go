@@ -0,0 +1,44 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TargetBand(t *testing.T) {
+	th := Thermostat{
+		TargetTemperatureC:     20,
+		TargetTemperatureF:     68,
+		TargetTemperatureLowC:  19,
+		TargetTemperatureLowF:  66,
+		TargetTemperatureHighC: 26,
+		TargetTemperatureHighF: 79,
+		EcoTemperatureLowC:     12.5,
+		EcoTemperatureLowF:     55,
+		EcoTemperatureHighC:    24,
+		EcoTemperatureHighF:    76,
+	}
+
+	tt := []struct {
+		mode      HvacMode
+		scale     TemperatureScale
+		low, high float64
+		ok        bool
+	}{
+		{HvacModeEco, Celsius, 12.5, 24, true},
+		{HvacModeEco, Fahrenheit, 55, 76, true},
+		{HvacModeHeatCool, Fahrenheit, 66, 79, true},
+		{HvacModeHeat, Celsius, 20, 20, true},
+		{HvacModeCool, Fahrenheit, 68, 68, true},
+		{HvacModeOff, Fahrenheit, 0, 0, false},
+	}
+
+	for _, tc := range tt {
+		th.HvacMode = tc.mode
+		low, high, ok := th.TargetBand(tc.scale)
+		assert.Equal(t, tc.low, low, string(tc.mode))
+		assert.Equal(t, tc.high, high, string(tc.mode))
+		assert.Equal(t, tc.ok, ok, string(tc.mode))
+	}
+}
@@ -0,0 +1,156 @@
+package nest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// errEmergencyHeat is returned when the HVAC mode cannot be changed because
+// the thermostat is using emergency heat.
+var errEmergencyHeat = errors.New("cannot change HVAC mode while emergency heat is on")
+
+// EnterEco switches the thermostat to Eco Temperatures. The Nest API records the
+// mode in effect as previous_hvac_mode, which ExitEco restores.
+// See https://developers.nest.com/guides/api/thermostat-guide#eco
+//
+func (svc *ThermostatService) EnterEco(deviceid string) error {
+	th, err := svc.Get(deviceid)
+	if err != nil {
+		return err
+	}
+	if th.HvacMode == device.HvacModeEco {
+		return nil
+	}
+	if th.IsUsingEmergencyHeat {
+		return errEmergencyHeat
+	}
+	return svc.SetHVACMode(deviceid, device.HvacModeEco)
+}
+
+// ExitEco restores the mode that was in effect before the thermostat entered
+// Eco Temperatures. When no previous mode is recorded, the most capable mode
+// the HVAC system supports is used.
+//
+func (svc *ThermostatService) ExitEco(deviceid string) error {
+	th, err := svc.Get(deviceid)
+	if err != nil {
+		return err
+	}
+	if th.HvacMode != device.HvacModeEco {
+		return nil
+	}
+	if th.IsUsingEmergencyHeat {
+		return errEmergencyHeat
+	}
+	return svc.SetHVACMode(deviceid, previousMode(th))
+}
+
+func previousMode(th *device.Thermostat) device.HvacMode {
+	if th.PreviousHvacMode != "" && th.PreviousHvacMode != device.HvacModeEco {
+		return th.PreviousHvacMode
+	}
+	switch {
+	case th.CanHeat && th.CanCool:
+		return device.HvacModeHeatCool
+	case th.CanHeat:
+		return device.HvacModeHeat
+	case th.CanCool:
+		return device.HvacModeCool
+	}
+	return device.HvacModeOff
+}
+
+// EmergencyHeatEvent reports that a thermostat started or stopped using emergency heat.
+type EmergencyHeatEvent struct {
+	DeviceID string
+	Active   bool
+	Time     time.Time
+}
+
+func (e EmergencyHeatEvent) String() string {
+	state := "off"
+	if e.Active {
+		state = "on"
+	}
+	return fmt.Sprintf("emergency heat %s: %s", state, e.DeviceID)
+}
+
+// WatchEmergencyHeat reads thermostat events from a Stream and emits an
+// EmergencyHeatEvent whenever is_using_emergency_heat changes. A thermostat
+// that is already using emergency heat when first seen is reported as active.
+// Events that do not carry is_using_emergency_heat are ignored. The returned
+// channel is closed when events is closed or ctx is done.
+func WatchEmergencyHeat(ctx context.Context, events <-chan Event) <-chan EmergencyHeatEvent {
+	out := make(chan EmergencyHeatEvent)
+	go func() {
+		defer close(out)
+		active := map[string]bool{}
+		for {
+			var event Event
+			var ok bool
+			select {
+			case event, ok = <-events:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			th, ok := thermostatFromEvent(event)
+			if !ok {
+				continue
+			}
+			using, ok := emergencyHeatFromEvent(event)
+			if !ok {
+				continue
+			}
+			prev, seen := active[th.DeviceID]
+			active[th.DeviceID] = using
+			if prev == using && (seen || !using) {
+				continue
+			}
+			select {
+			case out <- EmergencyHeatEvent{DeviceID: th.DeviceID, Active: using, Time: time.Now()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// emergencyHeatFromEvent returns is_using_emergency_heat from the data of a
+// thermostat event, and false when the event does not carry it.
+func emergencyHeatFromEvent(event Event) (using bool, ok bool) {
+	var v struct {
+		Data struct {
+			IsUsingEmergencyHeat *bool `json:"is_using_emergency_heat"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(event.Data(), &v); err != nil || v.Data.IsUsingEmergencyHeat == nil {
+		return false, false
+	}
+	return *v.Data.IsUsingEmergencyHeat, true
+}
+
+// thermostatFromEvent returns the thermostat carried by a stream event. The
+// device id from the event path is used when the data omits it.
+func thermostatFromEvent(event Event) (*device.Thermostat, bool) {
+	et, id, data, err := event.GetEvent()
+	if err != nil || et != Thermostats {
+		return nil, false
+	}
+	th, ok := data.(*device.Thermostat)
+	if !ok || th == nil {
+		return nil, false
+	}
+	if th.DeviceID == "" {
+		th.DeviceID = id
+	}
+	return th, true
+}
@@ -0,0 +1,87 @@
+package nest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EnterExitEco(t *testing.T) {
+	tt := []struct {
+		thermostat map[string]interface{}
+		writes     []string
+	}{
+		{map[string]interface{}{"hvac_mode": "heat", "previous_hvac_mode": ""}, []string{"hvac_mode=eco", "hvac_mode=heat"}},
+		{map[string]interface{}{"hvac_mode": "eco", "previous_hvac_mode": "cool"}, []string{"hvac_mode=cool"}},
+		{map[string]interface{}{"hvac_mode": "eco", "previous_hvac_mode": "", "can_heat": true, "can_cool": true}, []string{"hvac_mode=heat-cool"}},
+		{map[string]interface{}{"hvac_mode": "eco", "previous_hvac_mode": "", "can_heat": true}, []string{"hvac_mode=heat"}},
+	}
+
+	for _, tc := range tt {
+		s, ts := newThermostatServer(tc.thermostat)
+		svc := NewThermostatService(newTestClientWithServer(ts))
+		assert.NoError(t, svc.EnterEco("123"))
+		assert.NoError(t, svc.ExitEco("123"))
+		assert.NoError(t, svc.ExitEco("123"), "exiting eco twice should be a no-op")
+		assert.Equal(t, tc.writes, s.Writes())
+	}
+}
+
+func Test_EcoEmergencyHeat(t *testing.T) {
+	s, ts := newThermostatServer(map[string]interface{}{"hvac_mode": "heat", "is_using_emergency_heat": true})
+	svc := NewThermostatService(newTestClientWithServer(ts))
+	assert.Equal(t, errEmergencyHeat, svc.EnterEco("123"))
+
+	s.thermostat["hvac_mode"] = "eco"
+	assert.Equal(t, errEmergencyHeat, svc.ExitEco("123"))
+	assert.Empty(t, s.Writes())
+}
+
+func thermostatEvent(id string, emergencyHeat bool) Event {
+	return Event{
+		name: []byte("put"),
+		data: []byte(fmt.Sprintf(`{"path":"/devices/thermostats/%s","data":{"device_id":"%s","is_using_emergency_heat":%t}}`, id, id, emergencyHeat)),
+	}
+}
+
+func Test_WatchEmergencyHeat(t *testing.T) {
+	events := make(chan Event)
+	out := WatchEmergencyHeat(context.Background(), events)
+
+	go func() {
+		events <- thermostatEvent("1", false)
+		events <- thermostatEvent("1", false)
+		events <- Event{name: []byte(KeepAlive)}
+		// an update without is_using_emergency_heat is not a change
+		events <- Event{name: []byte("put"), data: []byte(`{"path":"/devices/thermostats/2","data":{"device_id":"2"}}`)}
+		events <- thermostatEvent("1", true)
+		events <- thermostatEvent("2", true)
+		events <- Event{name: []byte("put"), data: []byte(`{"path":"/devices/thermostats/2","data":{"target_temperature_f":70}}`)}
+		events <- thermostatEvent("1", true)
+		events <- thermostatEvent("1", false)
+		close(events)
+	}()
+
+	var got []string
+	for e := range out {
+		got = append(got, e.String())
+	}
+	assert.Equal(t, []string{
+		"emergency heat on: 1",
+		"emergency heat on: 2",
+		"emergency heat off: 1",
+	}, got)
+}
+
+func Test_WatchEmergencyHeatContext(t *testing.T) {
+	events := make(chan Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := WatchEmergencyHeat(ctx, events)
+
+	events <- thermostatEvent("1", true)
+	cancel()
+	for range out {
+	}
+}
@@ -3,13 +3,16 @@ package nest
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/jtsiros/nest/config"
 	"github.com/jtsiros/nest/device"
@@ -43,6 +46,19 @@ type Event struct {
 	data []byte
 }
 
+// NewEvent creates an event with the given name, such as "put", and data. It
+// allows events from sources other than a Stream to be handled like streamed
+// events.
+func NewEvent(name string, data []byte) Event {
+	return Event{name: []byte(name), data: data}
+}
+
+// Data returns the event data, the JSON object holding the path and data of
+// the change. Streamed updates may omit fields that did not change.
+func (e Event) Data() []byte {
+	return e.data
+}
+
 func (e Event) String() string {
 	return fmt.Sprintf("Event name: %v, Data: %v", string(e.name), string(e.data))
 }
@@ -76,6 +92,9 @@ func (e Event) GetEvent() (EventsType, string, interface{}, error) {
 
 	// /devices/thermostats/<id>
 	eventParsed := strings.Split(eventPathBuf.Path, "/")
+	if len(eventParsed) != 4 || eventParsed[0] != "" || eventParsed[1] != "devices" {
+		return EventError, "", nil, fmt.Errorf("unhandled event path: %q", eventPathBuf.Path)
+	}
 	deviceID := eventParsed[3]
 	eventType := EventsType(eventParsed[2])
 
@@ -99,11 +118,25 @@ func (e Event) GetEvent() (EventsType, string, interface{}, error) {
 	return eventType, deviceID, deviceData, nil
 }
 
+// ErrStreamClosed is returned when opening a stream that has been closed.
+var ErrStreamClosed = errors.New("stream closed")
+
 // Stream represents an open connection to the Nest APIs for device and structure changes.
 // This will maintain an open socket for every stream connected to a device.
 type Stream struct {
 	client  *http.Client
 	baseURL *url.URL
+
+	mu     sync.Mutex
+	closed bool
+	// cancels holds the cancel func of every connection being dialed or
+	// read, keyed by a connection id.
+	cancels map[int]context.CancelFunc
+	nextID  int
+	// onOpen and onClose are called, with mu held, when the stream starts its
+	// first connection and when its last connection ends or it is closed.
+	onOpen  func(*Stream)
+	onClose func(*Stream)
 }
 
 // NewStream returns a new stream given a configuration and http client objects.
@@ -119,26 +152,112 @@ func NewStream(cfg *config.Config, client *http.Client) (*Stream, error) {
 }
 
 // Open opens a connection and streams events from the Nest API.
-func (s Stream) Open() (chan Event, error) {
-	events := make(chan Event)
-	resp, err := s.createConnection()
+func (s *Stream) Open() (chan Event, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	id, err := s.addConnection(cancel)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	go readEvents(events, resp)
+
+	resp, err := s.createConnection(ctx)
+	if err == nil && s.isClosed() {
+		resp.Body.Close()
+		err = ErrStreamClosed
+	}
+	if err != nil {
+		cancel()
+		s.connectionDone(id)
+		if s.isClosed() {
+			return nil, ErrStreamClosed
+		}
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		readEvents(ctx, events, resp)
+		cancel()
+		s.connectionDone(id)
+	}()
 	return events, nil
 }
 
+// addConnection registers the cancel func of a connection before it is
+// dialed, so Close also aborts connections that are still connecting.
+func (s *Stream) addConnection(cancel context.CancelFunc) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	if s.cancels == nil {
+		s.cancels = map[int]context.CancelFunc{}
+	}
+	id := s.nextID
+	s.nextID++
+	s.cancels[id] = cancel
+	if len(s.cancels) == 1 && s.onOpen != nil {
+		s.onOpen(s)
+	}
+	return id, nil
+}
+
+// connectionDone records the end of a connection registered by addConnection.
+func (s *Stream) connectionDone(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cancels[id]; !ok {
+		// the stream was closed
+		return
+	}
+	delete(s.cancels, id)
+	if len(s.cancels) == 0 && s.onClose != nil {
+		s.onClose(s)
+	}
+}
+
+func (s *Stream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Close closes every connection opened by the stream. The channels returned by
+// Open are closed once their connection is torn down, and later calls to Open
+// return ErrStreamClosed.
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.cancels = nil
+	if s.onClose != nil {
+		s.onClose(s)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
 // readEvents listens on the socket for events. Events are expected to contain
 // event name, separated by newline, then event data.
 // Once the event is assembled, the event is written to the events channel for consumption.
-func readEvents(events chan<- Event, resp *http.Response) {
+func readEvents(ctx context.Context, events chan<- Event, resp *http.Response) {
+	defer close(events)
+	defer resp.Body.Close()
 	reader := bufio.NewReader(resp.Body)
 	event := Event{}
 	for {
 		line, err := reader.ReadBytes('\n')
 		if err != nil && err != io.EOF {
-			log.Printf("error reading response: %v\n", err)
+			if ctx.Err() == nil {
+				log.Printf("error reading response: %v\n", err)
+			}
 			break
 		}
 		if err == io.EOF {
@@ -150,34 +269,41 @@ func readEvents(events chan<- Event, resp *http.Response) {
 			event.name = extract(line, eventPfx)
 		case bytes.HasPrefix(line, dataPfx):
 			event.data = extract(line, dataPfx)
-			events <- event
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
 			event = Event{}
 		}
 	}
-	close(events)
 }
 
+// extract returns the value of a field line, e.g. "update" for "event: update\n".
+// A single space after the prefix and the line ending are removed. Nil is
+// returned when the line does not start with the prefix.
 func extract(line []byte, pfx []byte) []byte {
-	if len(line) == 0 {
+	if !bytes.HasPrefix(line, pfx) {
 		return nil
 	}
-	pfxIdx := len(pfx) + 1
-	endOfLineIdx := len(line) - 1
-	return line[pfxIdx:endOfLineIdx]
+	value := bytes.TrimSuffix(line[len(pfx):], []byte("\n"))
+	value = bytes.TrimSuffix(value, []byte("\r"))
+	return bytes.TrimPrefix(value, []byte(" "))
 }
 
 // createConnection opens an event-stream to the Nest API to receive events from devices.
 // This can be used to update the ambient temperature as it changes.
 //
-func (s Stream) createConnection() (*http.Response, error) {
+func (s *Stream) createConnection(ctx context.Context) (*http.Response, error) {
 	req, _ := http.NewRequest(http.MethodGet, s.baseURL.String(), nil)
 	req.Header.Add("Accept", "text/event-stream")
 
-	resp, err := s.client.Do(req)
+	resp, err := s.client.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to API: %v", err)
 	}
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil, fmt.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
 	}
 	return resp, nil
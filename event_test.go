@@ -1,6 +1,7 @@
 package nest
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
 
 	"github.com/jtsiros/nest/config"
 	"github.com/jtsiros/nest/device"
@@ -31,6 +34,9 @@ func Test_extract(t *testing.T) {
 		{[]byte("data: this is my data\n"), []byte("data:"), []byte("this is my data")},
 		{[]byte("event: update\n"), []byte("event:"), []byte("update")},
 		{[]byte(""), []byte("event:"), nil},
+		{[]byte("event:\n"), []byte("event:"), []byte{}},
+		{[]byte("data:no space\r\n"), []byte("data:"), []byte("no space")},
+		{[]byte("event"), []byte("event:"), nil},
 	}
 
 	for _, tc := range tt {
@@ -60,7 +66,7 @@ func Test_readEvents(t *testing.T) {
 		resp := tc.rec.Result()
 
 		events := make(chan Event)
-		go readEvents(events, resp)
+		go readEvents(context.Background(), events, resp)
 		event := <-events
 
 		assert.Equal(t, tc.expectedName, event.name)
@@ -78,6 +84,9 @@ func Test_getEvent(t *testing.T) {
 	unkown := createHandler("event: newdevice\ndata: {\"path\":\"/devices/newdevice/1234\",\"data\":{\"device_id\":\"1234\"}}\n")
 	invalidPath := createHandler("event: cameras\ndata: {\"path\"\n")
 	nullData := createHandler("event: thermostats\ndata: {\"path\":\"/devices/thermostats/1234\",\"data\":null}\n")
+	shortPath := createHandler("event: put\ndata: {\"path\":\"/devices\",\"data\":{}}\n")
+	rootPath := createHandler("event: put\ndata: {\"path\":\"/\",\"data\":{}}\n")
+	nullEvent := createHandler("event: put\ndata: null\n")
 
 	req := httptest.NewRequest("GET", "http://localhost/", nil)
 	tt := []struct {
@@ -95,6 +104,9 @@ func Test_getEvent(t *testing.T) {
 		{unkown, httptest.NewRecorder(), "", EventError, nil},
 		{invalidPath, httptest.NewRecorder(), "", EventError, nil},
 		{nullData, httptest.NewRecorder(), "1234", Thermostats, reflect.TypeOf(&device.Thermostat{})},
+		{shortPath, httptest.NewRecorder(), "", EventError, nil},
+		{rootPath, httptest.NewRecorder(), "", EventError, nil},
+		{nullEvent, httptest.NewRecorder(), "", EventError, nil},
 	}
 
 	for _, tc := range tt {
@@ -102,7 +114,7 @@ func Test_getEvent(t *testing.T) {
 		resp := tc.rec.Result()
 
 		events := make(chan Event)
-		go readEvents(events, resp)
+		go readEvents(context.Background(), events, resp)
 		event := <-events
 		et, deviceID, device, _ := event.GetEvent()
 		assert.Equal(t, tc.deviceID, deviceID)
@@ -111,6 +123,23 @@ func Test_getEvent(t *testing.T) {
 	}
 }
 
+// Test_readEventsRoundTrip checks that any event name and single-line data
+// written to a stream is read back unchanged.
+func Test_readEventsRoundTrip(t *testing.T) {
+	roundTrip := func(name, data string) bool {
+		name = strings.NewReplacer("\n", "", "\r", "").Replace(name)
+		data = strings.NewReplacer("\n", "", "\r", "").Replace(data)
+		body := fmt.Sprintf("event: %s\ndata: %s\n", name, data)
+		events := make(chan Event)
+		go readEvents(context.Background(), events, &http.Response{Body: ioutil.NopCloser(strings.NewReader(body))})
+		e, ok := <-events
+		return ok && string(e.name) == name && string(e.data) == data
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func Test_createConnection(t *testing.T) {
 	tsSuccess := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -132,7 +161,7 @@ func Test_createConnection(t *testing.T) {
 
 	for _, tc := range tt {
 		s, _ := NewStream(&config.Config{APIURL: tc.s.URL}, tc.s.Client())
-		resp, err := s.createConnection()
+		resp, err := s.createConnection(context.Background())
 		if tc.err != nil {
 			if tc.err.Error() != err.Error() {
 				t.Fatalf("expected err [%v] got [%v]\n", tc.err, err)
@@ -144,3 +173,12 @@ func Test_createConnection(t *testing.T) {
 		}
 	}
 }
+
+func Test_NewEvent(t *testing.T) {
+	e := NewEvent("put", []byte(`{"path":"/devices/cameras/123","data":{"is_streaming":true}}`))
+	et, id, data, err := e.GetEvent()
+	assert.NoError(t, err)
+	assert.Equal(t, Cameras, et)
+	assert.Equal(t, "123", id)
+	assert.True(t, data.(*device.Camera).IsStreaming)
+}
@@ -0,0 +1,49 @@
+//go:build go1.18
+// +build go1.18
+
+package nest
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func FuzzGetEvent(f *testing.F) {
+	f.Add("put", []byte(`{"path":"/devices/thermostats/1234","data":{"device_id":"1234"}}`))
+	f.Add("put", []byte(`{"path":"/devices/cameras/1234","data":{"last_event":{"has_motion":true}}}`))
+	f.Add("put", []byte(`{"path":"/devices","data":null}`))
+	f.Add("keep-alive", []byte("null"))
+	f.Add("put", []byte(`{"path":"/"}`))
+
+	f.Fuzz(func(t *testing.T, name string, data []byte) {
+		et, id, v, err := NewEvent(name, data).GetEvent()
+		if err != nil && et != EventError {
+			t.Errorf("expected %s type for error %v, got %s", EventError, err, et)
+		}
+		if err == nil && et != KeepAlive && v == nil {
+			t.Errorf("expected device data for %s %s", et, id)
+		}
+	})
+}
+
+func FuzzReadEvents(f *testing.F) {
+	f.Add([]byte("event: put\ndata: {\"path\":\"/devices/thermostats/1234\",\"data\":{}}\n\n"))
+	f.Add([]byte("event: keep-alive\ndata: null\n\n"))
+	f.Add([]byte("event:\ndata:\n"))
+	f.Add([]byte("data: {}\r\nevent"))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		events := make(chan Event)
+		resp := &http.Response{Body: ioutil.NopCloser(bytes.NewReader(body))}
+		go readEvents(context.Background(), events, resp)
+		for e := range events {
+			if bytes.ContainsAny(e.name, "\n") || bytes.ContainsAny(e.data, "\n") {
+				t.Errorf("event contains a line break: %v", e)
+			}
+			e.GetEvent()
+		}
+	})
+}
@@ -0,0 +1,413 @@
+// Package health monitors the battery and connectivity of Nest devices. It
+// polls Client.Devices() and applies stream updates, producing health reports
+// and alerting when a Protect's battery needs replacing or a device stays
+// offline longer than a grace period.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/backend"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/notify"
+)
+
+// Problem is a health problem of a device.
+type Problem string
+
+// Health problems
+const (
+	Offline        Problem = "offline"
+	BatteryReplace Problem = "battery_replace"
+)
+
+// DeviceHealth is the health of a single device. OfflineSince is when the
+// device was last seen online, taken from last_is_online_change or
+// last_connection, and is only set while the device is offline.
+type DeviceHealth struct {
+	DeviceID      string
+	Type          nest.EventsType
+	Name          string
+	Online        bool
+	OfflineSince  time.Time
+	BatteryHealth device.BatteryHealth
+	Problems      []Problem
+}
+
+// Healthy reports whether the device has no problems.
+func (d DeviceHealth) Healthy() bool {
+	return len(d.Problems) == 0
+}
+
+// Report is the health of every known device, ordered by type and device id.
+type Report struct {
+	Time    time.Time
+	Devices []DeviceHealth
+}
+
+// Unhealthy returns the devices that have at least one problem.
+func (r Report) Unhealthy() []DeviceHealth {
+	var unhealthy []DeviceHealth
+	for _, d := range r.Devices {
+		if !d.Healthy() {
+			unhealthy = append(unhealthy, d)
+		}
+	}
+	return unhealthy
+}
+
+// Alert is sent when a device develops a problem and again, with Resolved set,
+// when the problem goes away. Since is when the device was last seen online
+// for Offline alerts and when the problem was detected for other alerts.
+type Alert struct {
+	DeviceID string
+	Type     nest.EventsType
+	Name     string
+	Problem  Problem
+	Resolved bool
+	Since    time.Time
+	Time     time.Time
+}
+
+func (a Alert) String() string {
+	name := a.Name
+	if name == "" {
+		name = a.DeviceID
+	}
+	if a.Resolved {
+		return fmt.Sprintf("%s resolved: %s", a.Problem, name)
+	}
+	return fmt.Sprintf("%s: %s", a.Problem, name)
+}
+
+// Message describes the alert for notifiers. Resolved problems are
+// informational.
+func (a Alert) Message() notify.Message {
+	severity := notify.Warning
+	if a.Resolved {
+		severity = notify.Info
+	}
+	fields := map[string]string{
+		"type":    string(a.Type),
+		"problem": string(a.Problem),
+	}
+	if !a.Since.IsZero() {
+		fields["since"] = a.Since.Format(time.RFC3339)
+	}
+	return notify.Message{
+		Title:    a.String(),
+		Body:     a.String(),
+		Severity: severity,
+		DeviceID: a.DeviceID,
+		Time:     a.Time,
+		Fields:   fields,
+	}
+}
+
+type alertKey struct {
+	deviceID string
+	problem  Problem
+}
+
+// Monitor tracks device health and sends alerts.
+type Monitor struct {
+	lister    backend.Devices
+	grace     time.Duration
+	notifiers []notify.Notifier
+	now       func() time.Time
+
+	mu           sync.Mutex
+	devices      map[string]DeviceHealth
+	offlineSeen  map[string]time.Time
+	activeAlerts map[alertKey]Alert
+}
+
+// NewMonitor creates a monitor that lists devices with lister, e.g. a
+// nest.Client or sdm.Client, and alerts notifiers when a device has been
+// offline for longer than grace.
+func NewMonitor(lister backend.Devices, grace time.Duration, notifiers ...notify.Notifier) *Monitor {
+	return &Monitor{
+		lister:       lister,
+		grace:        grace,
+		notifiers:    notifiers,
+		now:          time.Now,
+		devices:      map[string]DeviceHealth{},
+		offlineSeen:  map[string]time.Time{},
+		activeAlerts: map[alertKey]Alert{},
+	}
+}
+
+// Check lists every device, sends due alerts and returns a health report.
+func (m *Monitor) Check(ctx context.Context) (Report, error) {
+	devices, err := m.lister.Devices()
+	if err != nil {
+		return Report{}, err
+	}
+
+	m.mu.Lock()
+	for id, t := range devices.Thermostats {
+		m.update(thermostatHealth(id, t), true)
+	}
+	for id, sa := range devices.SmokeCoAlarms {
+		m.update(smokeAlarmHealth(id, sa), true)
+	}
+	for id, c := range devices.Cameras {
+		m.update(cameraHealth(id, c), true)
+	}
+	due := m.evaluate()
+	r := m.report()
+	m.mu.Unlock()
+	return r, m.deliver(ctx, due)
+}
+
+// Observe applies a device update received on a Stream and sends due alerts.
+// Events for other data are ignored. An update without is_online keeps the
+// connectivity last known for the device.
+func (m *Monitor) Observe(ctx context.Context, event nest.Event) error {
+	_, id, data, err := event.GetEvent()
+	if err != nil {
+		return nil
+	}
+	var online struct {
+		Data struct {
+			IsOnline *bool `json:"is_online"`
+		} `json:"data"`
+	}
+	hasOnline := json.Unmarshal(event.Data(), &online) == nil && online.Data.IsOnline != nil
+
+	m.mu.Lock()
+	switch d := data.(type) {
+	case *device.Thermostat:
+		if d != nil {
+			m.update(thermostatHealth(id, d), hasOnline)
+		}
+	case *device.SmokeAlarm:
+		if d != nil {
+			m.update(smokeAlarmHealth(id, d), hasOnline)
+		}
+	case *device.Camera:
+		if d != nil {
+			m.update(cameraHealth(id, d), hasOnline)
+		}
+	default:
+		m.mu.Unlock()
+		return nil
+	}
+	due := m.evaluate()
+	m.mu.Unlock()
+	return m.deliver(ctx, due)
+}
+
+// Run calls Check every interval and observes events until ctx is done or
+// events is closed. events may be nil when only polling is wanted. Errors are
+// logged.
+func (m *Monitor) Run(ctx context.Context, events <-chan nest.Event, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	if _, err := m.Check(ctx); err != nil {
+		log.Printf("error checking device health: %v\n", err)
+	}
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, err = m.Check(ctx)
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			err = m.Observe(ctx, event)
+		}
+		if err != nil {
+			log.Printf("error checking device health: %v\n", err)
+		}
+	}
+}
+
+// Report returns the health of every known device without listing devices.
+func (m *Monitor) Report() Report {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.report()
+}
+
+// update stores the health of a device. hasOnline reports whether d.Online
+// was set from the device data; when it was not, the previous connectivity is
+// kept, and a device seen for the first time is assumed to be online until a
+// listing or update says otherwise.
+func (m *Monitor) update(d DeviceHealth, hasOnline bool) {
+	if d.DeviceID == "" {
+		return
+	}
+	prev, ok := m.devices[d.DeviceID]
+	if ok {
+		// stream updates may omit fields reported by a full listing
+		if d.Name == "" {
+			d.Name = prev.Name
+		}
+		if d.BatteryHealth == "" {
+			d.BatteryHealth = prev.BatteryHealth
+		}
+	}
+	if !hasOnline {
+		d.Online = !ok || prev.Online
+		if d.Online {
+			d.OfflineSince = time.Time{}
+		} else if d.OfflineSince.IsZero() {
+			d.OfflineSince = prev.OfflineSince
+		}
+	}
+	m.devices[d.DeviceID] = d
+}
+
+// delivery is an alert due to be sent to notifiers. The active alerts are
+// updated once the alert is sent, so an alert that failed to send is sent
+// again on the next evaluation.
+type delivery struct {
+	key   alertKey
+	alert Alert
+}
+
+// evaluate computes the problems of every device and returns the alerts due
+// for new and resolved problems.
+func (m *Monitor) evaluate() []delivery {
+	now := m.now()
+	var due []delivery
+	for id, d := range m.devices {
+		d.Problems = nil
+		if d.BatteryHealth == device.BatteryHealthReplace {
+			d.Problems = append(d.Problems, BatteryReplace)
+		}
+		offlineSince := d.OfflineSince
+		if d.Online {
+			delete(m.offlineSeen, id)
+		} else {
+			if offlineSince.IsZero() {
+				if _, ok := m.offlineSeen[id]; !ok {
+					m.offlineSeen[id] = now
+				}
+				offlineSince = m.offlineSeen[id]
+			}
+			if now.Sub(offlineSince) >= m.grace {
+				d.Problems = append(d.Problems, Offline)
+			}
+		}
+		m.devices[id] = d
+
+		for _, p := range []Problem{BatteryReplace, Offline} {
+			key := alertKey{deviceID: id, problem: p}
+			prev, active := m.activeAlerts[key]
+			has := hasProblem(d, p)
+			if has == active {
+				continue
+			}
+			alert := Alert{DeviceID: id, Type: d.Type, Name: d.Name, Problem: p, Since: now, Time: now}
+			if p == Offline {
+				alert.Since = offlineSince
+			}
+			if !has {
+				alert.Resolved = true
+				alert.Since = prev.Since
+			}
+			due = append(due, delivery{key: key, alert: alert})
+		}
+	}
+	return due
+}
+
+// deliver sends due alerts without holding the lock and records the alerts
+// that were sent. The first error is returned.
+func (m *Monitor) deliver(ctx context.Context, due []delivery) error {
+	var firstErr error
+	for _, d := range due {
+		if err := m.send(ctx, d.alert); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		m.mu.Lock()
+		_, active := m.activeAlerts[d.key]
+		switch {
+		case !d.alert.Resolved && !active:
+			m.activeAlerts[d.key] = d.alert
+		case d.alert.Resolved && active:
+			delete(m.activeAlerts, d.key)
+		}
+		m.mu.Unlock()
+	}
+	return firstErr
+}
+
+func (m *Monitor) send(ctx context.Context, a Alert) error {
+	var firstErr error
+	for _, n := range m.notifiers {
+		if err := n.Notify(ctx, a.Message()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m *Monitor) report() Report {
+	r := Report{Time: m.now()}
+	for _, d := range m.devices {
+		r.Devices = append(r.Devices, d)
+	}
+	sort.Slice(r.Devices, func(i, j int) bool {
+		if r.Devices[i].Type != r.Devices[j].Type {
+			return r.Devices[i].Type < r.Devices[j].Type
+		}
+		return r.Devices[i].DeviceID < r.Devices[j].DeviceID
+	})
+	return r
+}
+
+func hasProblem(d DeviceHealth, p Problem) bool {
+	for _, problem := range d.Problems {
+		if problem == p {
+			return true
+		}
+	}
+	return false
+}
+
+func thermostatHealth(id string, t *device.Thermostat) DeviceHealth {
+	d := DeviceHealth{DeviceID: deviceID(id, t.DeviceID), Type: nest.Thermostats, Name: t.NameLong, Online: t.IsOnline}
+	if !t.IsOnline {
+		d.OfflineSince = t.LastConnection
+	}
+	return d
+}
+
+func smokeAlarmHealth(id string, sa *device.SmokeAlarm) DeviceHealth {
+	d := DeviceHealth{DeviceID: deviceID(id, sa.DeviceID), Type: nest.SmokeCoAlarms, Name: sa.NameLong, Online: sa.IsOnline, BatteryHealth: sa.BatteryHealth}
+	if !sa.IsOnline {
+		d.OfflineSince = sa.LastConnection
+	}
+	return d
+}
+
+func cameraHealth(id string, c *device.Camera) DeviceHealth {
+	d := DeviceHealth{DeviceID: deviceID(id, c.DeviceID), Type: nest.Cameras, Name: c.NameLong, Online: c.IsOnline}
+	if !c.IsOnline {
+		d.OfflineSince = c.LastIsOnlineChange
+	}
+	return d
+}
+
+func deviceID(id, fromData string) string {
+	if fromData != "" {
+		return fromData
+	}
+	return id
+}
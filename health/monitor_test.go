@@ -0,0 +1,231 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/config"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+type lister struct {
+	devices *device.Devices
+	err     error
+}
+
+func (l *lister) Devices() (*device.Devices, error) {
+	return l.devices, l.err
+}
+
+type recorder struct {
+	alerts []string
+}
+
+func (r *recorder) Notify(ctx context.Context, m notify.Message) error {
+	r.alerts = append(r.alerts, m.Title)
+	return nil
+}
+
+func Test_MonitorCheck(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+
+	protect := &device.SmokeAlarm{DeviceID: "p1", NameLong: "Hallway Nest Protect", IsOnline: true, BatteryHealth: device.BatteryHealthReplace}
+	thermostat := &device.Thermostat{DeviceID: "t1", NameLong: "Living Room Thermostat", LastConnection: now.Add(-10 * time.Minute)}
+	camera := &device.Camera{DeviceID: "c1", NameLong: "Front Door Camera", LastIsOnlineChange: now.Add(-2 * time.Hour)}
+	l := &lister{devices: &device.Devices{
+		Thermostats:   map[string]*device.Thermostat{"t1": thermostat},
+		SmokeCoAlarms: map[string]*device.SmokeAlarm{"p1": protect},
+		Cameras:       map[string]*device.Camera{"c1": camera},
+	}}
+
+	r := &recorder{}
+	m := NewMonitor(l, time.Hour, r)
+	m.now = func() time.Time { return now }
+
+	report, err := m.Check(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, report.Devices, 3)
+	unhealthy := report.Unhealthy()
+	if assert.Len(t, unhealthy, 2) {
+		assert.Equal(t, "c1", unhealthy[0].DeviceID)
+		assert.Equal(t, []Problem{Offline}, unhealthy[0].Problems)
+		assert.Equal(t, camera.LastIsOnlineChange, unhealthy[0].OfflineSince)
+		assert.Equal(t, "p1", unhealthy[1].DeviceID)
+		assert.Equal(t, []Problem{BatteryReplace}, unhealthy[1].Problems)
+	}
+
+	// the thermostat is reported once it has been offline beyond the grace period
+	now = now.Add(time.Hour)
+	_, err = m.Check(ctx)
+	assert.NoError(t, err)
+
+	// problems are resolved
+	protect.BatteryHealth = device.BatteryHealthOK
+	camera.IsOnline = true
+	_, err = m.Check(ctx)
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{
+		"offline: Front Door Camera",
+		"battery_replace: Hallway Nest Protect",
+	}, r.alerts[:2])
+	assert.Equal(t, "offline: Living Room Thermostat", r.alerts[2])
+	assert.ElementsMatch(t, []string{
+		"offline resolved: Front Door Camera",
+		"battery_replace resolved: Hallway Nest Protect",
+	}, r.alerts[3:])
+}
+
+func Test_MonitorOfflineWithoutTimestamp(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	l := &lister{devices: &device.Devices{
+		Thermostats: map[string]*device.Thermostat{"t1": {DeviceID: "t1"}},
+	}}
+	r := &recorder{}
+	m := NewMonitor(l, 5*time.Minute, r)
+	m.now = func() time.Time { return now }
+
+	// the grace period starts when the device is first seen offline
+	for i := 0; i < 5; i++ {
+		_, err := m.Check(ctx)
+		assert.NoError(t, err)
+		now = now.Add(time.Minute)
+	}
+	assert.Empty(t, r.alerts)
+
+	_, err := m.Check(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"offline: t1"}, r.alerts)
+}
+
+func Test_MonitorCheckError(t *testing.T) {
+	m := NewMonitor(&lister{err: errors.New("unreachable")}, time.Minute)
+	_, err := m.Check(context.Background())
+	assert.EqualError(t, err, "unreachable")
+}
+
+func Test_MonitorNotifyError(t *testing.T) {
+	ctx := context.Background()
+	l := &lister{devices: &device.Devices{
+		SmokeCoAlarms: map[string]*device.SmokeAlarm{"s1": {DeviceID: "s1", IsOnline: true, BatteryHealth: device.BatteryHealthReplace}},
+	}}
+	var alerts []string
+	fail := true
+	m := NewMonitor(l, time.Hour, notify.NotifierFunc(func(ctx context.Context, msg notify.Message) error {
+		if fail {
+			return errors.New("notifier unavailable")
+		}
+		alerts = append(alerts, msg.Title)
+		return nil
+	}))
+
+	_, err := m.Check(ctx)
+	assert.EqualError(t, err, "notifier unavailable")
+
+	fail = false
+	_, err = m.Check(ctx)
+	assert.NoError(t, err)
+	_, err = m.Check(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"battery_replace: s1"}, alerts, "an alert that failed to send should be retried once")
+
+	l.devices.SmokeCoAlarms["s1"].BatteryHealth = device.BatteryHealthOK
+	fail = true
+	_, err = m.Check(ctx)
+	assert.Error(t, err)
+	fail = false
+	_, err = m.Check(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"battery_replace: s1", "battery_replace resolved: s1"}, alerts, "a resolution that failed to send should be retried")
+}
+
+func Test_MonitorRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, online := range []bool{true, false} {
+			fmt.Fprintf(w, "event: put\ndata: {\"path\":\"/devices/cameras/c1\",\"data\":{\"is_online\":%t,\"last_is_online_change\":\"2020-03-24T09:00:00.000Z\"}}\n", online)
+		}
+	}))
+	defer ts.Close()
+
+	s, _ := nest.NewStream(&config.Config{APIURL: ts.URL}, ts.Client())
+	events, err := s.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var alerts []notify.Message
+	l := &lister{devices: &device.Devices{
+		Cameras: map[string]*device.Camera{"c1": {DeviceID: "c1", NameLong: "Front Door Camera", IsOnline: true}},
+	}}
+	m := NewMonitor(l, time.Hour, notify.NotifierFunc(func(ctx context.Context, m notify.Message) error {
+		alerts = append(alerts, m)
+		return nil
+	}))
+	m.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+
+	assert.NoError(t, m.Run(context.Background(), events, time.Hour))
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "c1", alerts[0].DeviceID)
+		assert.Equal(t, "offline: Front Door Camera", alerts[0].Title, "name should be kept from the device listing")
+		assert.Equal(t, "cameras", alerts[0].Fields["type"])
+	}
+	assert.False(t, m.Report().Devices[0].Healthy())
+}
+
+func Test_MonitorObservePartialUpdate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	l := &lister{devices: &device.Devices{
+		SmokeCoAlarms: map[string]*device.SmokeAlarm{"p1": {DeviceID: "p1", NameLong: "Hallway Nest Protect", IsOnline: true}},
+	}}
+	var alerts []notify.Message
+	m := NewMonitor(l, 0, notify.NotifierFunc(func(ctx context.Context, m notify.Message) error {
+		alerts = append(alerts, m)
+		return nil
+	}))
+	m.now = func() time.Time { return now }
+	_, err := m.Check(ctx)
+	assert.NoError(t, err)
+
+	// an update without is_online keeps the device online
+	assert.NoError(t, m.Observe(ctx, nest.NewEvent("put", []byte(`{"path":"/devices/smoke_co_alarms/p1","data":{"battery_health":"replace"}}`))))
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "battery_replace: Hallway Nest Protect", alerts[0].Title)
+		assert.Equal(t, "2020-03-24T11:00:00Z", alerts[0].Fields["since"], "battery alerts should be since the problem was detected")
+	}
+	assert.True(t, m.Report().Devices[0].Online)
+
+	assert.NoError(t, m.Observe(ctx, nest.NewEvent("put", []byte(`{"path":"/devices/smoke_co_alarms/p1","data":{"is_online":false,"last_connection":"2020-03-24T10:00:00.000Z"}}`))))
+	if assert.Len(t, alerts, 2) {
+		assert.Equal(t, "offline: Hallway Nest Protect", alerts[1].Title)
+		assert.Equal(t, "2020-03-24T10:00:00Z", alerts[1].Fields["since"])
+	}
+
+	// and keeps an offline device offline
+	assert.NoError(t, m.Observe(ctx, nest.NewEvent("put", []byte(`{"path":"/devices/smoke_co_alarms/p1","data":{"battery_health":"ok"}}`))))
+	if assert.Len(t, alerts, 3) {
+		assert.Equal(t, "battery_replace resolved: Hallway Nest Protect", alerts[2].Title)
+	}
+	assert.False(t, m.Report().Devices[0].Online)
+}
+
+func Test_AlertMessage(t *testing.T) {
+	since := time.Date(2020, 3, 24, 9, 0, 0, 0, time.UTC)
+	m := (Alert{DeviceID: "c1", Type: nest.Cameras, Name: "Front Door", Problem: Offline, Since: since}).Message()
+	assert.Equal(t, "offline: Front Door", m.Title)
+	assert.Equal(t, notify.Warning, m.Severity)
+	assert.Equal(t, map[string]string{"type": "cameras", "problem": "offline", "since": "2020-03-24T09:00:00Z"}, m.Fields)
+
+	m = (Alert{DeviceID: "c1", Problem: Offline, Resolved: true}).Message()
+	assert.Equal(t, notify.Info, m.Severity)
+}
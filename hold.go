@@ -0,0 +1,315 @@
+package nest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/internal/fileutil"
+)
+
+// Hold is a temporary target temperature on a thermostat. It records the
+// settings in effect before the hold so they can be restored once it expires.
+type Hold struct {
+	DeviceID       string                  `json:"device_id"`
+	Scale          device.TemperatureScale `json:"scale"`
+	Until          time.Time               `json:"until"`
+	PreviousMode   device.HvacMode         `json:"previous_mode"`
+	PreviousTarget int                     `json:"previous_target,omitempty"`
+	PreviousLow    int                     `json:"previous_low,omitempty"`
+	PreviousHigh   int                     `json:"previous_high,omitempty"`
+}
+
+// HoldStore persists active holds so they can be reverted after a process restart.
+type HoldStore interface {
+	Holds() ([]Hold, error)
+	SaveHold(Hold) error
+	DeleteHold(deviceid string) error
+}
+
+// SetHold sets a temporary target temperature until the given time. When mode
+// is not empty the thermostat is also switched to mode, which must be heat or
+// cool. The previous mode and target are saved to store and restored by
+// ReleaseHold or ReleaseExpiredHolds. Setting a hold on a thermostat that is
+// already held extends it without changing the settings to be restored.
+//
+func (svc *ThermostatService) SetHold(store HoldStore, deviceid string, mode device.HvacMode, scale device.TemperatureScale, target int, until time.Time) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	if mode != "" && mode != device.HvacModeHeat && mode != device.HvacModeCool {
+		return fmt.Errorf("cannot hold target temperature in %s mode", mode)
+	}
+
+	th, err := svc.Get(deviceid)
+	if err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = th.HvacMode
+	}
+	if mode != device.HvacModeHeat && mode != device.HvacModeCool {
+		return fmt.Errorf("cannot hold target temperature in %s mode", mode)
+	}
+
+	hold, err := findHold(store, deviceid)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		hold = &Hold{
+			DeviceID:       deviceid,
+			Scale:          scale,
+			PreviousMode:   th.HvacMode,
+			PreviousTarget: currentTarget(th, scale),
+			PreviousLow:    currentLow(th, scale),
+			PreviousHigh:   currentHigh(th, scale),
+		}
+	}
+	hold.Until = until
+	if err := store.SaveHold(*hold); err != nil {
+		return err
+	}
+
+	if mode != th.HvacMode {
+		if err := svc.SetHVACMode(deviceid, mode); err != nil {
+			return err
+		}
+	}
+	return svc.SetTargetTemperature(deviceid, scale, target)
+}
+
+// SetHoldFor sets a temporary target temperature for the given duration. See SetHold.
+func (svc *ThermostatService) SetHoldFor(store HoldStore, deviceid string, mode device.HvacMode, scale device.TemperatureScale, target int, d time.Duration) error {
+	return svc.SetHold(store, deviceid, mode, scale, target, time.Now().Add(d))
+}
+
+// ReleaseHold restores the settings saved by SetHold and removes the hold from store.
+func (svc *ThermostatService) ReleaseHold(store HoldStore, deviceid string) error {
+	hold, err := findHold(store, deviceid)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return fmt.Errorf("no hold for device: %s", deviceid)
+	}
+	if err := svc.restore(*hold); err != nil {
+		return err
+	}
+	return store.DeleteHold(deviceid)
+}
+
+// ReleaseExpiredHolds releases every hold in store that expired at or before now.
+// All holds are attempted; the first error is returned and failed holds are
+// kept in store to be retried.
+func (svc *ThermostatService) ReleaseExpiredHolds(store HoldStore, now time.Time) error {
+	holds, err := store.Holds()
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, hold := range holds {
+		if hold.Until.After(now) {
+			continue
+		}
+		err := svc.restore(hold)
+		if err == nil {
+			err = store.DeleteHold(hold.DeviceID)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("device %s: %v", hold.DeviceID, err)
+		}
+	}
+	return firstErr
+}
+
+// WatchHolds releases expired holds every interval until ctx is done. Holds
+// saved by a previous process are picked up from store, so calling WatchHolds
+// on startup reverts holds that expired while the process was not running.
+func (svc *ThermostatService) WatchHolds(ctx context.Context, store HoldStore, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := svc.ReleaseExpiredHolds(store, time.Now()); err != nil {
+			log.Printf("error releasing holds: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (svc *ThermostatService) restore(hold Hold) error {
+	th, err := svc.Get(hold.DeviceID)
+	if err != nil {
+		return err
+	}
+	if hold.PreviousMode != "" && hold.PreviousMode != th.HvacMode {
+		if err := svc.SetHVACMode(hold.DeviceID, hold.PreviousMode); err != nil {
+			return err
+		}
+	}
+	switch hold.PreviousMode {
+	case device.HvacModeHeat, device.HvacModeCool:
+		if hold.PreviousTarget != 0 {
+			return svc.SetTargetTemperature(hold.DeviceID, hold.Scale, hold.PreviousTarget)
+		}
+	case device.HvacModeHeatCool:
+		if hold.PreviousLow != 0 || hold.PreviousHigh != 0 {
+			return svc.SetTargetTemperatureRange(hold.DeviceID, hold.Scale, hold.PreviousLow, hold.PreviousHigh)
+		}
+	}
+	return nil
+}
+
+func findHold(store HoldStore, deviceid string) (*Hold, error) {
+	holds, err := store.Holds()
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holds {
+		if h.DeviceID == deviceid {
+			return &h, nil
+		}
+	}
+	return nil, nil
+}
+
+func currentLow(th *device.Thermostat, scale device.TemperatureScale) int {
+	if scale == device.Celsius {
+		return int(math.Round(th.TargetTemperatureLowC))
+	}
+	return th.TargetTemperatureLowF
+}
+
+func currentHigh(th *device.Thermostat, scale device.TemperatureScale) int {
+	if scale == device.Celsius {
+		return int(math.Round(th.TargetTemperatureHighC))
+	}
+	return th.TargetTemperatureHighF
+}
+
+// MemoryHoldStore keeps holds in memory.
+type MemoryHoldStore struct {
+	mu    sync.Mutex
+	holds map[string]Hold
+}
+
+// NewMemoryHoldStore creates an empty in-memory hold store.
+func NewMemoryHoldStore() *MemoryHoldStore {
+	return &MemoryHoldStore{holds: map[string]Hold{}}
+}
+
+// Holds returns all holds.
+func (m *MemoryHoldStore) Holds() ([]Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	holds := make([]Hold, 0, len(m.holds))
+	for _, h := range m.holds {
+		holds = append(holds, h)
+	}
+	return holds, nil
+}
+
+// SaveHold adds or replaces the hold for a device.
+func (m *MemoryHoldStore) SaveHold(h Hold) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.holds[h.DeviceID] = h
+	return nil
+}
+
+// DeleteHold removes the hold for a device.
+func (m *MemoryHoldStore) DeleteHold(deviceid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.holds, deviceid)
+	return nil
+}
+
+// FileHoldStore persists holds as JSON to a file.
+type FileHoldStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileHoldStore creates a hold store backed by the file at path. The file is
+// created when the first hold is saved.
+func NewFileHoldStore(path string) *FileHoldStore {
+	return &FileHoldStore{path: path}
+}
+
+// Holds returns all holds saved in the file.
+func (f *FileHoldStore) Holds() ([]Hold, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	holds, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Hold, 0, len(holds))
+	for _, h := range holds {
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+// SaveHold adds or replaces the hold for a device.
+func (f *FileHoldStore) SaveHold(h Hold) error {
+	if h.DeviceID == "" {
+		return errors.New("hold requires a device id")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	holds, err := f.read()
+	if err != nil {
+		return err
+	}
+	holds[h.DeviceID] = h
+	return f.write(holds)
+}
+
+// DeleteHold removes the hold for a device.
+func (f *FileHoldStore) DeleteHold(deviceid string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	holds, err := f.read()
+	if err != nil {
+		return err
+	}
+	delete(holds, deviceid)
+	return f.write(holds)
+}
+
+func (f *FileHoldStore) read() (map[string]Hold, error) {
+	holds := map[string]Hold{}
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return holds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &holds); err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+func (f *FileHoldStore) write(holds map[string]Hold) error {
+	b, err := json.MarshalIndent(holds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(f.path, b, 0644)
+}
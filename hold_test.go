@@ -0,0 +1,138 @@
+package nest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+// thermostatServer serves a single thermostat and records the values written to it.
+type thermostatServer struct {
+	mu         sync.Mutex
+	thermostat map[string]interface{}
+	writes     []string
+	fail       int
+}
+
+func newThermostatServer(thermostat map[string]interface{}) (*thermostatServer, *httptest.Server) {
+	s := &thermostatServer{thermostat: thermostat}
+	return s, httptest.NewServer(s)
+}
+
+func (s *thermostatServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Method == http.MethodGet {
+		_ = json.NewEncoder(w).Encode(s.thermostat)
+		return
+	}
+	if s.fail > 0 {
+		s.fail--
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":"blocked","message":"blocked"}`)
+		return
+	}
+	var values map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&values)
+	keys := make([]string, 0, len(values))
+	for k, v := range values {
+		// like the Nest API, remember the mode in effect when entering eco
+		if k == "hvac_mode" && v == "eco" {
+			s.thermostat["previous_hvac_mode"] = s.thermostat["hvac_mode"]
+		}
+		s.thermostat[k] = v
+		keys = append(keys, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(keys)
+	s.writes = append(s.writes, strings.Join(keys, ","))
+	_ = json.NewEncoder(w).Encode(values)
+}
+
+func (s *thermostatServer) Writes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.writes...)
+}
+
+func Test_SetHoldAndRelease(t *testing.T) {
+	s, ts := newThermostatServer(map[string]interface{}{
+		"device_id":                 "123",
+		"hvac_mode":                 "heat-cool",
+		"target_temperature_low_f":  65,
+		"target_temperature_high_f": 75,
+	})
+	svc := NewThermostatService(newTestClientWithServer(ts))
+	store := NewMemoryHoldStore()
+
+	until := time.Now().Add(time.Hour)
+	assert.NoError(t, svc.SetHold(store, "123", Heat, F, 70, until))
+	assert.NoError(t, svc.SetHold(store, "123", "", F, 72, until.Add(time.Hour)))
+
+	holds, _ := store.Holds()
+	assert.Len(t, holds, 1)
+	assert.Equal(t, device.HvacModeHeatCool, holds[0].PreviousMode, "extending a hold should keep the original settings")
+	assert.Equal(t, 65, holds[0].PreviousLow)
+	assert.Equal(t, 75, holds[0].PreviousHigh)
+	assert.True(t, until.Add(time.Hour).Equal(holds[0].Until))
+
+	assert.NoError(t, svc.ReleaseHold(store, "123"))
+	assert.Equal(t, []string{
+		"hvac_mode=heat",
+		"target_temperature_f=70",
+		"target_temperature_f=72",
+		"hvac_mode=heat-cool",
+		"target_temperature_high_f=75,target_temperature_low_f=65",
+	}, s.Writes())
+
+	holds, _ = store.Holds()
+	assert.Empty(t, holds)
+	assert.EqualError(t, svc.ReleaseHold(store, "123"), "no hold for device: 123")
+}
+
+func Test_SetHoldValidation(t *testing.T) {
+	_, ts := newThermostatServer(map[string]interface{}{"device_id": "123", "hvac_mode": "off"})
+	svc := NewThermostatService(newTestClientWithServer(ts))
+	store := NewMemoryHoldStore()
+
+	assert.EqualError(t, svc.SetHoldFor(store, "123", "", F, 70, time.Hour), "cannot hold target temperature in off mode")
+	assert.EqualError(t, svc.SetHoldFor(store, "123", Eco, F, 70, time.Hour), "cannot hold target temperature in eco mode")
+	assert.EqualError(t, svc.SetHoldFor(store, "123", Heat, "K", 70, time.Hour), `invalid temperature scale: "K"`)
+}
+
+func Test_ReleaseExpiredHolds(t *testing.T) {
+	s, ts := newThermostatServer(map[string]interface{}{"device_id": "123", "hvac_mode": "heat", "target_temperature_f": 66})
+	svc := NewThermostatService(newTestClientWithServer(ts))
+
+	dir, err := ioutil.TempDir("", "holds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "holds.json")
+
+	now := time.Now()
+	assert.NoError(t, svc.SetHold(NewFileHoldStore(path), "123", "", F, 72, now.Add(time.Hour)))
+
+	// a new store reading the same file simulates a process restart
+	store := NewFileHoldStore(path)
+	assert.NoError(t, svc.ReleaseExpiredHolds(store, now))
+	holds, _ := store.Holds()
+	assert.Len(t, holds, 1, "hold should not be released before it expires")
+
+	assert.NoError(t, svc.ReleaseExpiredHolds(store, now.Add(2*time.Hour)))
+	holds, _ = store.Holds()
+	assert.Empty(t, holds)
+	assert.Equal(t, []string{"target_temperature_f=72", "target_temperature_f=66"}, s.Writes())
+}
@@ -0,0 +1,53 @@
+// Package devcopy copies devices and structures. It is shared by the fake
+// backends, backend.Fake and nesttest.Server, so the state they hold is never
+// shared with their callers.
+package devcopy
+
+import "github.com/jtsiros/nest/device"
+
+// Camera returns a copy of a camera that shares no memory with it.
+func Camera(camera *device.Camera) *device.Camera {
+	c := *camera
+	if camera.ActivityZones != nil {
+		c.ActivityZones = make([]*device.ActivityZone, len(camera.ActivityZones))
+		for i, z := range camera.ActivityZones {
+			if z != nil {
+				zc := *z
+				c.ActivityZones[i] = &zc
+			}
+		}
+	}
+	if camera.LastEvent != nil {
+		e := *camera.LastEvent
+		e.ActivityZoneIDs = Strings(e.ActivityZoneIDs)
+		c.LastEvent = &e
+	}
+	return &c
+}
+
+// Structure returns a copy of a structure that shares no memory with it.
+func Structure(st *device.Structure) *device.Structure {
+	c := *st
+	c.Thermostats = Strings(st.Thermostats)
+	c.SmokeCoAlarms = Strings(st.SmokeCoAlarms)
+	c.Cameras = Strings(st.Cameras)
+	if st.Wheres != nil {
+		c.Wheres = make(map[string]*device.Where, len(st.Wheres))
+		for id, w := range st.Wheres {
+			if w != nil {
+				wc := *w
+				w = &wc
+			}
+			c.Wheres[id] = w
+		}
+	}
+	return &c
+}
+
+// Strings returns a copy of s, keeping nil slices nil.
+func Strings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
@@ -0,0 +1,37 @@
+package devcopy
+
+import (
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Camera(t *testing.T) {
+	camera := &device.Camera{
+		DeviceID:      "c1",
+		ActivityZones: []*device.ActivityZone{{ID: 1, Name: "Driveway"}, nil},
+		LastEvent:     &device.CameraEvent{HasMotion: true, ActivityZoneIDs: []string{"1"}},
+	}
+	c := Camera(camera)
+	assert.Equal(t, camera, c)
+
+	c.ActivityZones[0].Name = "changed"
+	c.LastEvent.HasMotion = false
+	c.LastEvent.ActivityZoneIDs[0] = "changed"
+	assert.Equal(t, "Driveway", camera.ActivityZones[0].Name)
+	assert.True(t, camera.LastEvent.HasMotion)
+	assert.Equal(t, []string{"1"}, camera.LastEvent.ActivityZoneIDs)
+}
+
+func Test_Structure(t *testing.T) {
+	st := &device.Structure{StructureID: "s1", Cameras: []string{"c1"}, Wheres: map[string]*device.Where{"w1": {WhereID: "w1", Name: "Den"}}}
+	c := Structure(st)
+	assert.Equal(t, st, c)
+	assert.Nil(t, c.Thermostats, "nil lists should stay nil")
+
+	c.Cameras[0] = "changed"
+	c.Wheres["w1"].Name = "changed"
+	assert.Equal(t, []string{"c1"}, st.Cameras)
+	assert.Equal(t, "Den", st.Wheres["w1"].Name)
+}
@@ -0,0 +1,35 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data.json")
+	assert.NoError(t, WriteFile(path, []byte("one"), 0600))
+	assert.NoError(t, WriteFile(path, []byte("two"), 0600))
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(b))
+
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "temporary files should be renamed")
+
+	assert.Error(t, WriteFile(filepath.Join(dir, "missing", "data.json"), nil, 0600))
+}
@@ -0,0 +1,155 @@
+// Package rules implements the validation the Nest API applies to thermostat
+// writes. It is shared by the fake backends, backend.Fake and nesttest.Server,
+// so both accept and reject the same writes.
+package rules
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// Temperature limits of the Nest API.
+const (
+	MinTempF, MaxTempF = 50, 90
+	MinTempC, MaxTempC = 9.0, 32.0
+	MinRangeF          = 3
+	MinRangeC          = 1.5
+)
+
+// fanTimerDurations are the fan timer durations, in minutes, the Nest API accepts.
+var fanTimerDurations = map[int]bool{15: true, 30: true, 45: true, 60: true, 120: true, 240: true, 480: true, 720: true}
+
+// Error is a rejected write. Err is the error reported by the Nest API and
+// Message its description.
+type Error struct {
+	Err     string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InvalidValue returns the error for a field written with an invalid value.
+func InvalidValue(field string) *Error {
+	return &Error{Err: "Invalid value", Message: fmt.Sprintf("Invalid value for '%s'", field)}
+}
+
+// CheckFanTimerDuration validates a fan timer duration in minutes.
+func CheckFanTimerDuration(d int) *Error {
+	if !fanTimerDurations[d] {
+		return InvalidValue("fan_timer_duration")
+	}
+	return nil
+}
+
+// SetHvacMode changes the mode of a thermostat, recording the previous mode
+// when entering eco.
+func SetHvacMode(th *device.Thermostat, mode device.HvacMode) *Error {
+	switch {
+	case th.IsUsingEmergencyHeat:
+		return &Error{Err: "Emergency heat", Message: "Cannot change HVAC mode while using emergency heat"}
+	case mode == device.HvacModeHeat && !th.CanHeat,
+		mode == device.HvacModeCool && !th.CanCool,
+		mode == device.HvacModeHeatCool && !(th.CanHeat && th.CanCool):
+		return &Error{Err: "Invalid mode", Message: fmt.Sprintf("Thermostat is not configured for %s mode", mode)}
+	}
+	if mode == device.HvacModeEco && th.HvacMode != device.HvacModeEco {
+		th.PreviousHvacMode = th.HvacMode
+	}
+	th.HvacMode = mode
+	return nil
+}
+
+// SetTargetTemperature changes the target temperature of a thermostat in heat
+// or cool mode.
+func SetTargetTemperature(th *device.Thermostat, scale device.TemperatureScale, t float64) *Error {
+	switch {
+	case th.IsUsingEmergencyHeat:
+		return emergencyHeat()
+	case th.HvacMode != device.HvacModeHeat && th.HvacMode != device.HvacModeCool:
+		return &Error{Err: "Invalid mode", Message: fmt.Sprintf("Cannot change target temperature while in %s mode", th.HvacMode)}
+	}
+	if err := checkTemperature(th, scale, t); err != nil {
+		return err
+	}
+	th.TargetTemperatureC, th.TargetTemperatureF = Temperatures(scale, t)
+	return nil
+}
+
+// SetTargetTemperatureRange changes the low and high target temperatures of a
+// thermostat in heat-cool mode. A nil low or high keeps the current value,
+// which must still be far enough from the new one.
+func SetTargetTemperatureRange(th *device.Thermostat, scale device.TemperatureScale, low, high *float64) *Error {
+	switch {
+	case th.IsUsingEmergencyHeat:
+		return emergencyHeat()
+	case th.HvacMode != device.HvacModeHeatCool:
+		return &Error{Err: "Invalid mode", Message: fmt.Sprintf("Cannot change target temperature range while in %s mode", th.HvacMode)}
+	}
+	lowC, lowF := th.TargetTemperatureLowC, float64(th.TargetTemperatureLowF)
+	highC, highF := th.TargetTemperatureHighC, float64(th.TargetTemperatureHighF)
+	if low != nil {
+		if err := checkTemperature(th, scale, *low); err != nil {
+			return err
+		}
+		lowC, lowF = *low, *low
+	}
+	if high != nil {
+		if err := checkTemperature(th, scale, *high); err != nil {
+			return err
+		}
+		highC, highF = *high, *high
+	}
+	if scale == device.Fahrenheit && highF-lowF < MinRangeF ||
+		scale == device.Celsius && highC-lowC < MinRangeC {
+		return &Error{Err: "Invalid range", Message: "Low and high target temperatures are too close"}
+	}
+	if low != nil {
+		th.TargetTemperatureLowC, th.TargetTemperatureLowF = Temperatures(scale, *low)
+	}
+	if high != nil {
+		th.TargetTemperatureHighC, th.TargetTemperatureHighF = Temperatures(scale, *high)
+	}
+	return nil
+}
+
+func emergencyHeat() *Error {
+	return &Error{Err: "Emergency heat", Message: "Cannot change target temperature while using emergency heat"}
+}
+
+// checkTemperature validates a target temperature against the limits of the
+// API and, when the thermostat is locked, its locked range.
+func checkTemperature(th *device.Thermostat, scale device.TemperatureScale, t float64) *Error {
+	min, max := float64(MinTempF), float64(MaxTempF)
+	if scale == device.Celsius {
+		min, max = MinTempC, MaxTempC
+	}
+	if th.IsLocked {
+		if scale == device.Celsius {
+			min, max = th.LockedTempMinC, th.LockedTempMaxC
+		} else {
+			min, max = float64(th.LockedTempMinF), float64(th.LockedTempMaxF)
+		}
+	}
+	switch {
+	case t < min:
+		return &Error{Err: "Temperature too low", Message: fmt.Sprintf("Temperature %s value is too low: %v", scale, t)}
+	case t > max:
+		return &Error{Err: "Temperature too high", Message: fmt.Sprintf("Temperature %s value is too high: %v", scale, t)}
+	}
+	return nil
+}
+
+// Temperatures returns a temperature in Celsius, rounded to half a degree as
+// Nest does, and in Fahrenheit.
+func Temperatures(scale device.TemperatureScale, t float64) (float64, int) {
+	if scale == device.Celsius {
+		c := math.Round(t*2) / 2
+		return c, int(math.Round(c*9/5 + 32))
+	}
+	f := math.Round(t)
+	return math.Round((f-32)*5/9*2) / 2, int(f)
+}
@@ -0,0 +1,56 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Temperatures(t *testing.T) {
+	tests := []struct {
+		scale device.TemperatureScale
+		t     float64
+		c     float64
+		f     int
+	}{
+		{device.Fahrenheit, 70, 21, 70},
+		{device.Fahrenheit, 71.4, 21.5, 71},
+		{device.Celsius, 21, 21, 70},
+		{device.Celsius, 21.3, 21.5, 71},
+	}
+	for _, tt := range tests {
+		c, f := Temperatures(tt.scale, tt.t)
+		assert.Equal(t, tt.c, c, "%v%s", tt.t, tt.scale)
+		assert.Equal(t, tt.f, f, "%v%s", tt.t, tt.scale)
+	}
+}
+
+func Test_SetTargetTemperatureRange(t *testing.T) {
+	float := func(f float64) *float64 { return &f }
+	th := &device.Thermostat{HvacMode: device.HvacModeHeatCool, TargetTemperatureLowF: 68, TargetTemperatureHighF: 74}
+
+	tests := []struct {
+		name      string
+		locked    bool
+		low, high *float64
+		err       string
+	}{
+		{"too close to the current high", false, float(73), nil, "Low and high target temperatures are too close"},
+		{"inverted", false, float(75), float(70), "Low and high target temperatures are too close"},
+		{"too cold", false, float(45), nil, "Temperature F value is too low: 45"},
+		{"outside the locked range", true, nil, float(80), "Temperature F value is too high: 80"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *th
+			c.IsLocked, c.LockedTempMinF, c.LockedTempMaxF = tt.locked, 65, 76
+			assert.EqualError(t, SetTargetTemperatureRange(&c, device.Fahrenheit, tt.low, tt.high), tt.err)
+		})
+	}
+
+	assert.Nil(t, SetTargetTemperatureRange(th, device.Fahrenheit, nil, float(78)))
+	assert.Equal(t, 68, th.TargetTemperatureLowF)
+	assert.Equal(t, 78, th.TargetTemperatureHighF)
+	assert.Equal(t, 25.5, th.TargetTemperatureHighC)
+}
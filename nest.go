@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/jtsiros/nest/config"
 	"github.com/jtsiros/nest/device"
@@ -24,6 +26,23 @@ type Client struct {
 	Thermostats   *ThermostatService
 	SmokeCoAlarms *SmokeCoAlarmService
 	Cameras       *CameraService
+	Structures    *StructureService
+
+	// MinRampInterval is the minimum time between writes made by
+	// ThermostatService.RampTargetTemperature. Defaults to DefaultMinRampInterval.
+	MinRampInterval time.Duration
+	// MaxMediaSize is the maximum number of bytes read from camera media.
+	// Defaults to DefaultMaxMediaSize.
+	MaxMediaSize int64
+	// MediaClient fetches camera media from hosts other than the API host.
+	// Media URLs are signed, so it must not add credentials. Defaults to the
+	// client's HTTP client with an OAuth2 transport replaced by its base
+	// transport; set it when credentials are added in another way.
+	MediaClient *http.Client
+
+	revokeURL string
+	mu        sync.Mutex
+	streams   map[*Stream]struct{}
 }
 
 // Error represents an error from API call
@@ -32,6 +51,9 @@ type Error struct {
 	Type     string `json:"type"`
 	Message  string `json:"message"`
 	Instance string `json:"instance"`
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int `json:"-"`
 }
 
 func (e Error) Error() string {
@@ -49,11 +71,14 @@ func NewClient(config config.Config, client *http.Client) (*Client, error) {
 	c := &Client{
 		baseURL:    url,
 		httpClient: client,
+		revokeURL:  config.WithDefaults().RevokeURL,
+		streams:    map[*Stream]struct{}{},
 	}
 
 	c.Cameras = NewCameraService(c)
 	c.Thermostats = NewThermostatService(c)
 	c.SmokeCoAlarms = NewSmokeCoAlarmService(c)
+	c.Structures = NewStructureService(c)
 
 	return c, nil
 }
@@ -111,7 +136,7 @@ func (nest *Client) do(req *http.Request, v interface{}) (*http.Response, error)
 		return nil, err
 	}
 	if resp.StatusCode != http.StatusOK {
-		var err Error
+		err := Error{StatusCode: resp.StatusCode}
 		if err := json.NewDecoder(resp.Body).Decode(&err); err != nil {
 			return nil, err
 		}
@@ -124,6 +149,17 @@ func (nest *Client) do(req *http.Request, v interface{}) (*http.Response, error)
 	return resp, err
 }
 
+// requestWithValues sends values as the JSON body of a request to path and discards the response.
+func (nest *Client) requestWithValues(method string, path string, values map[string]interface{}) error {
+	req, err := nest.newRequest(method, path, values)
+	if err != nil {
+		return err
+	}
+
+	_, err = nest.do(req, nil)
+	return err
+}
+
 func (nest *Client) getDevice(deviceid string, url string, device interface{}) error {
 	req, err := nest.newRequest("GET", fmt.Sprintf("%s/%s", url, deviceid), nil)
 	if err != nil {
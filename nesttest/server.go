@@ -0,0 +1,462 @@
+// Package nesttest provides an in-memory fake of the Nest REST API for tests.
+// A Server holds device and structure state, serves the root, device and
+// structure GETs, applies PUTs with the validation rules of the Nest API and
+// streams changes as server-sent events. Errors, latency and redirects can be
+// injected with faults, and a Simulator makes its thermostats heat, cool and
+// drift on a controllable clock.
+package nesttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/config"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/internal/devcopy"
+)
+
+// redirectPrefix is prepended to the path of redirected requests.
+const redirectPrefix = "/redirected"
+
+// Fault is injected into requests matching Method and Path. An empty Method
+// matches every method and Path matches as a prefix, so an empty Path matches
+// every request. Latency delays the request. When Redirect is set the request
+// is answered with a 307 redirect, as the Nest API does when it moves a client
+// to another host. Otherwise a non-zero StatusCode fails the request with Error
+// as the body. Times limits how many requests the fault applies to; zero
+// applies it until the faults are cleared.
+type Fault struct {
+	Method     string
+	Path       string
+	Latency    time.Duration
+	Redirect   bool
+	StatusCode int
+	Error      nest.Error
+	Times      int
+}
+
+// Server is a fake Nest API server. The embedded httptest.Server provides the
+// URL and an HTTP client.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	devices     device.Devices
+	structures  map[string]*device.Structure
+	faults      []*Fault
+	subscribers map[*subscriber]struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	now         func() time.Time
+}
+
+// NewServer starts a fake Nest API server with no devices. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		devices: device.Devices{
+			Thermostats:   map[string]*device.Thermostat{},
+			SmokeCoAlarms: map[string]*device.SmokeAlarm{},
+			Cameras:       map[string]*device.Camera{},
+		},
+		structures:  map[string]*device.Structure{},
+		subscribers: map[*subscriber]struct{}{},
+		done:        make(chan struct{}),
+		now:         time.Now,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close ends open streams and shuts down the server.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.Server.Close()
+}
+
+// Config returns a configuration pointing at the server.
+func (s *Server) Config() config.Config {
+	return config.Config{APIURL: s.URL}
+}
+
+// NewClient returns a Nest client for the server.
+func (s *Server) NewClient() (*nest.Client, error) {
+	return nest.NewClient(s.Config(), s.Client())
+}
+
+// Inject adds a fault. Faults are applied in the order they were added.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// AddThermostat adds or replaces a thermostat. Its structure, if added, lists it.
+func (s *Server) AddThermostat(th *device.Thermostat) {
+	c := *th
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices.Thermostats[c.DeviceID] = &c
+	if st, ok := s.structures[c.StructureID]; ok {
+		st.Thermostats = appendID(st.Thermostats, c.DeviceID)
+	}
+	s.publish(thermostatPath(c.DeviceID), &c)
+}
+
+// AddSmokeCoAlarm adds or replaces a Smoke+CO Alarm.
+func (s *Server) AddSmokeCoAlarm(sa *device.SmokeAlarm) {
+	c := *sa
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices.SmokeCoAlarms[c.DeviceID] = &c
+	if st, ok := s.structures[c.StructureID]; ok {
+		st.SmokeCoAlarms = appendID(st.SmokeCoAlarms, c.DeviceID)
+	}
+	s.publish(smokeCoAlarmPath(c.DeviceID), &c)
+}
+
+// AddCamera adds or replaces a camera. Its structure, if added, lists it.
+func (s *Server) AddCamera(camera *device.Camera) {
+	c := devcopy.Camera(camera)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices.Cameras[c.DeviceID] = c
+	if st, ok := s.structures[c.StructureID]; ok {
+		st.Cameras = appendID(st.Cameras, c.DeviceID)
+	}
+	s.publish(cameraPath(c.DeviceID), c)
+}
+
+// AddStructure adds or replaces a structure. Devices of the structure that
+// were added before it are added to its device lists, so devices and
+// structures can be added in any order.
+func (s *Server) AddStructure(st *device.Structure) {
+	c := devcopy.Structure(st)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var thermostats, smokeCoAlarms, cameras []string
+	for id, th := range s.devices.Thermostats {
+		if th.StructureID == c.StructureID {
+			thermostats = append(thermostats, id)
+		}
+	}
+	for id, sa := range s.devices.SmokeCoAlarms {
+		if sa.StructureID == c.StructureID {
+			smokeCoAlarms = append(smokeCoAlarms, id)
+		}
+	}
+	for id, camera := range s.devices.Cameras {
+		if camera.StructureID == c.StructureID {
+			cameras = append(cameras, id)
+		}
+	}
+	c.Thermostats = appendIDs(c.Thermostats, thermostats)
+	c.SmokeCoAlarms = appendIDs(c.SmokeCoAlarms, smokeCoAlarms)
+	c.Cameras = appendIDs(c.Cameras, cameras)
+	s.structures[c.StructureID] = c
+}
+
+// Thermostat returns a copy of a thermostat.
+func (s *Server) Thermostat(deviceid string) (*device.Thermostat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	th, ok := s.devices.Thermostats[deviceid]
+	if !ok {
+		return nil, false
+	}
+	c := *th
+	return &c, true
+}
+
+// SmokeCoAlarm returns a copy of a Smoke+CO Alarm.
+func (s *Server) SmokeCoAlarm(deviceid string) (*device.SmokeAlarm, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sa, ok := s.devices.SmokeCoAlarms[deviceid]
+	if !ok {
+		return nil, false
+	}
+	c := *sa
+	return &c, true
+}
+
+// Camera returns a copy of a camera.
+func (s *Server) Camera(deviceid string) (*device.Camera, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	camera, ok := s.devices.Cameras[deviceid]
+	if !ok {
+		return nil, false
+	}
+	return devcopy.Camera(camera), true
+}
+
+// Structure returns a copy of a structure.
+func (s *Server) Structure(structureid string) (*device.Structure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.structures[structureid]
+	if !ok {
+		return nil, false
+	}
+	return devcopy.Structure(st), true
+}
+
+// UpdateThermostat changes a thermostat as the device itself would, without
+// validation, and streams the change. It reports whether the thermostat exists.
+func (s *Server) UpdateThermostat(deviceid string, fn func(th *device.Thermostat)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	th, ok := s.devices.Thermostats[deviceid]
+	if ok {
+		fn(th)
+		s.publish(thermostatPath(deviceid), th)
+	}
+	return ok
+}
+
+// UpdateSmokeCoAlarm changes a Smoke+CO Alarm, e.g. to raise an alarm, and
+// streams the change. It reports whether the alarm exists.
+func (s *Server) UpdateSmokeCoAlarm(deviceid string, fn func(sa *device.SmokeAlarm)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sa, ok := s.devices.SmokeCoAlarms[deviceid]
+	if ok {
+		fn(sa)
+		s.publish(smokeCoAlarmPath(deviceid), sa)
+	}
+	return ok
+}
+
+// UpdateCamera changes a camera, e.g. to start an event, and streams the
+// change. It reports whether the camera exists.
+func (s *Server) UpdateCamera(deviceid string, fn func(camera *device.Camera)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	camera, ok := s.devices.Cameras[deviceid]
+	if ok {
+		fn(camera)
+		s.publish(cameraPath(deviceid), camera)
+	}
+	return ok
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, redirectPrefix)
+	if f := s.fault(r.Method, path); f != nil {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+		switch {
+		case f.Redirect:
+			u := *r.URL
+			u.Path = redirectPrefix + path
+			http.Redirect(w, r, u.String(), http.StatusTemporaryRedirect)
+			return
+		case f.StatusCode != 0:
+			writeError(w, f.StatusCode, f.Error)
+			return
+		}
+	}
+
+	path = strings.TrimSuffix(path, "/")
+	switch {
+	case r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/event-stream"):
+		s.stream(w, r, path)
+	case r.Method == http.MethodGet:
+		s.get(w, path)
+	case r.Method == http.MethodPut:
+		s.put(w, r, path)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, nest.Error{Err: "method not allowed", Message: "method not allowed"})
+	}
+}
+
+// fault returns the first fault matching the request and counts its use.
+func (s *Server) fault(method, path string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != method) || !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) get(w http.ResponseWriter, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.lookup(path)
+	if !ok {
+		writeError(w, http.StatusNotFound, notFound(path))
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// lookup returns the value at path in the data model.
+func (s *Server) lookup(path string) (interface{}, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	switch {
+	case path == "":
+		return struct {
+			Devices    device.Devices               `json:"devices"`
+			Structures map[string]*device.Structure `json:"structures"`
+		}{s.devices, s.structures}, true
+	case path == "/devices":
+		return s.devices, true
+	case path == "/structures":
+		return s.structures, true
+	case len(parts) == 2 && parts[0] == "structures":
+		st, ok := s.structures[parts[1]]
+		return st, ok
+	case len(parts) == 2 && parts[0] == "devices":
+		switch nest.EventsType(parts[1]) {
+		case nest.Thermostats:
+			return s.devices.Thermostats, true
+		case nest.SmokeCoAlarms:
+			return s.devices.SmokeCoAlarms, true
+		case nest.Cameras:
+			return s.devices.Cameras, true
+		}
+	case len(parts) == 3 && parts[0] == "devices":
+		switch nest.EventsType(parts[1]) {
+		case nest.Thermostats:
+			th, ok := s.devices.Thermostats[parts[2]]
+			return th, ok
+		case nest.SmokeCoAlarms:
+			sa, ok := s.devices.SmokeCoAlarms[parts[2]]
+			return sa, ok
+		case nest.Cameras:
+			camera, ok := s.devices.Cameras[parts[2]]
+			return camera, ok
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, path string) {
+	var values map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&values); err != nil || len(values) == 0 {
+		writeError(w, http.StatusBadRequest, invalidContent())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	var err *nest.Error
+	switch {
+	case len(parts) == 3 && parts[0] == "devices" && parts[1] == string(nest.Thermostats):
+		th, ok := s.devices.Thermostats[parts[2]]
+		if !ok {
+			writeError(w, http.StatusNotFound, notFound(path))
+			return
+		}
+		c := *th
+		if err = applyThermostat(&c, values, s.now()); err == nil {
+			*th = c
+			s.publish(path, th)
+		}
+	case len(parts) == 3 && parts[0] == "devices" && parts[1] == string(nest.Cameras):
+		camera, ok := s.devices.Cameras[parts[2]]
+		if !ok {
+			writeError(w, http.StatusNotFound, notFound(path))
+			return
+		}
+		c := *camera
+		if err = applyCamera(&c, values); err == nil {
+			*camera = c
+			s.publish(path, camera)
+		}
+	case len(parts) == 2 && parts[0] == "structures":
+		st, ok := s.structures[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, notFound(path))
+			return
+		}
+		c := *st
+		if err = applyStructure(&c, values); err == nil {
+			*st = c
+		}
+	default:
+		if _, ok := s.lookup(path); !ok {
+			writeError(w, http.StatusNotFound, notFound(path))
+			return
+		}
+		err = notWritable(parts[len(parts)-1])
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, *err)
+		return
+	}
+	writeJSON(w, http.StatusOK, values)
+}
+
+func thermostatPath(deviceid string) string {
+	return "/devices/" + string(nest.Thermostats) + "/" + deviceid
+}
+
+func smokeCoAlarmPath(deviceid string) string {
+	return "/devices/" + string(nest.SmokeCoAlarms) + "/" + deviceid
+}
+
+func cameraPath(deviceid string) string {
+	return "/devices/" + string(nest.Cameras) + "/" + deviceid
+}
+
+// appendIDs appends the ids missing from a device list in sorted order.
+func appendIDs(ids []string, add []string) []string {
+	sort.Strings(add)
+	for _, id := range add {
+		ids = appendID(ids, id)
+	}
+	return ids
+}
+
+func appendID(ids []string, id string) []string {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err nest.Error) {
+	writeJSON(w, code, err)
+}
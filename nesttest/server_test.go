@@ -0,0 +1,210 @@
+package nesttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, *nest.Client) {
+	s := NewServer()
+	s.AddStructure(&device.Structure{StructureID: "s1", Name: "Home", Away: device.AwayStateHome})
+	s.AddThermostat(&device.Thermostat{
+		DeviceID:           "t1",
+		StructureID:        "s1",
+		HvacMode:           device.HvacModeHeat,
+		CanHeat:            true,
+		CanCool:            true,
+		HasFan:             true,
+		TargetTemperatureF: 68,
+		TargetTemperatureC: 20,
+	})
+	s.AddCamera(&device.Camera{DeviceID: "c1", StructureID: "s1"})
+	s.AddSmokeCoAlarm(&device.SmokeAlarm{DeviceID: "p1", StructureID: "s1", CoAlarmState: device.AlarmStateOK})
+	n, err := s.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, n
+}
+
+func Test_ServerGet(t *testing.T) {
+	s, n := newTestServer(t)
+	defer s.Close()
+
+	devices, err := n.Devices()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, devices.Len())
+
+	th, err := n.Thermostats.Get("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, 68, th.TargetTemperatureF)
+
+	st, err := n.Structures.Get("s1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"t1"}, st.Thermostats)
+
+	_, err = n.Cameras.Get("unknown")
+	if assert.IsType(t, nest.Error{}, err) {
+		assert.Equal(t, http.StatusNotFound, err.(nest.Error).StatusCode)
+	}
+}
+
+func Test_ServerCopies(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	// devices added before their structure are linked to it
+	camera := &device.Camera{DeviceID: "c1", StructureID: "s1", ActivityZones: []*device.ActivityZone{{ID: 1, Name: "Driveway"}}}
+	s.AddCamera(camera)
+	s.AddThermostat(&device.Thermostat{DeviceID: "t2", StructureID: "s1"})
+	s.AddThermostat(&device.Thermostat{DeviceID: "t1", StructureID: "s1"})
+	structure := &device.Structure{StructureID: "s1", Thermostats: []string{"t2"}, Wheres: map[string]*device.Where{"w1": {WhereID: "w1", Name: "Den"}}}
+	s.AddStructure(structure)
+	s.AddSmokeCoAlarm(&device.SmokeAlarm{DeviceID: "p1", StructureID: "s1"})
+
+	st, _ := s.Structure("s1")
+	assert.Equal(t, []string{"t2", "t1"}, st.Thermostats)
+	assert.Equal(t, []string{"c1"}, st.Cameras)
+	assert.Equal(t, []string{"p1"}, st.SmokeCoAlarms)
+	assert.Equal(t, []string{"t2"}, structure.Thermostats, "the added structure should not be changed")
+
+	// neither the added nor the returned devices share memory with the server
+	camera.ActivityZones[0].Name = "changed"
+	structure.Wheres["w1"].Name = "changed"
+	c, _ := s.Camera("c1")
+	assert.Equal(t, "Driveway", c.ActivityZones[0].Name)
+	c.ActivityZones[0].Name = "changed"
+	st.Wheres["w1"].Name = "changed"
+	st.Cameras[0] = "changed"
+
+	c, _ = s.Camera("c1")
+	assert.Equal(t, "Driveway", c.ActivityZones[0].Name)
+	st, _ = s.Structure("s1")
+	assert.Equal(t, "Den", st.Wheres["w1"].Name)
+	assert.Equal(t, []string{"c1"}, st.Cameras)
+}
+
+func Test_ServerPut(t *testing.T) {
+	s, n := newTestServer(t)
+	defer s.Close()
+
+	assert.NoError(t, n.Thermostats.SetTargetTemperature("t1", device.Fahrenheit, 72))
+	th, _ := s.Thermostat("t1")
+	assert.Equal(t, 72, th.TargetTemperatureF)
+	assert.Equal(t, 22.0, th.TargetTemperatureC)
+
+	tests := []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{"too hot", n.Thermostats.SetTargetTemperature("t1", device.Fahrenheit, 95), "Temperature F value is too high: 95"},
+		{"range in heat mode", n.Thermostats.SetTargetTemperatureRange("t1", device.Celsius, 18, 22), "Cannot change target temperature range while in heat mode"},
+		{"fan duration", n.Thermostats.SetFanTimerDuration("t1", 90), "Invalid value for 'fan_timer_duration'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.err, tt.msg)
+		})
+	}
+
+	assert.NoError(t, n.Thermostats.SetHVACMode("t1", device.HvacModeHeatCool))
+	assert.EqualError(t, n.Thermostats.SetTargetTemperatureRange("t1", device.Fahrenheit, 70, 71), "Low and high target temperatures are too close")
+	assert.NoError(t, n.Thermostats.SetTargetTemperatureRange("t1", device.Celsius, 18, 22))
+	th, _ = s.Thermostat("t1")
+	assert.Equal(t, 64, th.TargetTemperatureLowF)
+	assert.Equal(t, 72, th.TargetTemperatureHighF)
+
+	assert.NoError(t, n.Thermostats.EnterEco("t1"))
+	th, _ = s.Thermostat("t1")
+	assert.Equal(t, device.HvacModeHeatCool, th.PreviousHvacMode)
+
+	s.UpdateThermostat("t1", func(th *device.Thermostat) { th.IsUsingEmergencyHeat = true })
+	assert.EqualError(t, n.Thermostats.SetHVACMode("t1", device.HvacModeHeat), "Cannot change HVAC mode while using emergency heat")
+
+	assert.NoError(t, n.Cameras.SetStreaming("c1", true))
+	camera, _ := s.Camera("c1")
+	assert.True(t, camera.IsStreaming)
+
+	req, _ := http.NewRequest(http.MethodPut, s.URL+"/devices/thermostats/t1", strings.NewReader(`{"humidity": 40}`))
+	resp, err := s.Client().Do(req)
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		var apiErr nest.Error
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "Field 'humidity' is not writable", apiErr.Message)
+	}
+}
+
+func Test_ServerStream(t *testing.T) {
+	s, n := newTestServer(t)
+	defer s.Close()
+
+	stream, err := n.Thermostats.Stream("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	events, err := stream.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() *device.Thermostat {
+		select {
+		case e := <-events:
+			et, id, data, err := e.GetEvent()
+			assert.NoError(t, err)
+			assert.Equal(t, nest.Thermostats, et)
+			assert.Equal(t, "t1", id)
+			return data.(*device.Thermostat)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return nil
+	}
+
+	assert.Equal(t, 68, next().TargetTemperatureF, "the current state should be streamed first")
+	assert.NoError(t, n.Cameras.SetStreaming("c1", true))
+	assert.NoError(t, n.Thermostats.SetTargetTemperature("t1", device.Fahrenheit, 70))
+	assert.Equal(t, 70, next().TargetTemperatureF, "only changes to the thermostat should be streamed")
+}
+
+func Test_ServerFaults(t *testing.T) {
+	s, n := newTestServer(t)
+	defer s.Close()
+
+	s.Inject(Fault{Method: http.MethodPut, StatusCode: http.StatusTooManyRequests, Error: nest.Error{Err: "blocked", Message: "Rate limit exceeded"}, Times: 1})
+	err := n.Thermostats.SetLabel("t1", "Hallway")
+	if assert.IsType(t, nest.Error{}, err) {
+		assert.Equal(t, http.StatusTooManyRequests, err.(nest.Error).StatusCode)
+		assert.Equal(t, "Rate limit exceeded", err.Error())
+	}
+	assert.NoError(t, n.Thermostats.SetLabel("t1", "Hallway"), "the fault should only apply once")
+
+	s.Inject(Fault{Path: "/devices/thermostats", Redirect: true, Times: 2})
+	assert.NoError(t, n.Thermostats.SetLabel("t1", "Living Room"), "redirected PUTs should be applied")
+	th, err := n.Thermostats.Get("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Living Room", th.Label)
+
+	s.Inject(Fault{Latency: 50 * time.Millisecond})
+	start := time.Now()
+	_, err = n.Devices()
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	s.ClearFaults()
+	start = time.Now()
+	_, err = n.Devices()
+	assert.NoError(t, err)
+	assert.True(t, time.Since(start) < 50*time.Millisecond)
+}
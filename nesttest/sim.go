@@ -0,0 +1,242 @@
+package nesttest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// Model describes how a home heated and cooled by a thermostat responds. Rates
+// are per hour.
+type Model struct {
+	// Leak is the fraction of the difference between the outside and ambient
+	// temperature the home gains or loses per hour.
+	Leak float64
+	// HeatRate and CoolRate are how fast the HVAC system heats and cools, in
+	// degrees Celsius.
+	HeatRate float64
+	CoolRate float64
+	// Humidity is the relative humidity the home drifts toward while the HVAC
+	// system is idle, and Dehumidify how many points cooling removes.
+	Humidity   float64
+	Dehumidify float64
+}
+
+// DefaultModel is a moderately insulated home with a typical furnace and air
+// conditioner.
+var DefaultModel = Model{
+	Leak:       0.1,
+	HeatRate:   2,
+	CoolRate:   1.5,
+	Humidity:   45,
+	Dehumidify: 3,
+}
+
+// hysteresis is how far, in degrees Celsius, the ambient temperature may fall
+// below or rise above the target before heating or cooling starts.
+const hysteresis = 0.5
+
+type simState struct {
+	ambientC float64
+	humidity float64
+}
+
+// Simulator simulates the thermostats of a Server on a controllable clock.
+// Each step the ambient temperature drifts toward the outside temperature and
+// is driven by heating or cooling, which sets hvac_state and time_to_target.
+// Active fan timers expire and PUTs are timestamped with the simulated time.
+type Simulator struct {
+	server *Server
+	now    time.Time
+
+	// Outside is the outside temperature in degrees Celsius.
+	Outside float64
+	// Step is the simulated interval between updates. One minute is used when
+	// it is not positive.
+	Step time.Duration
+
+	models map[string]Model
+	states map[string]*simState
+}
+
+// NewSimulator creates a simulator for the thermostats of s starting at the
+// given time. The server's clock follows the simulator.
+func NewSimulator(s *Server, start time.Time) *Simulator {
+	sim := &Simulator{
+		server:  s,
+		now:     start,
+		Outside: 10,
+		Step:    time.Minute,
+		models:  map[string]Model{},
+		states:  map[string]*simState{},
+	}
+	s.mu.Lock()
+	s.now = sim.Now
+	s.mu.Unlock()
+	return sim
+}
+
+// Now returns the simulated time.
+func (sim *Simulator) Now() time.Time {
+	return sim.now
+}
+
+// SetModel sets the model of a thermostat. Thermostats without a model use
+// DefaultModel.
+func (sim *Simulator) SetModel(deviceid string, m Model) {
+	sim.models[deviceid] = m
+}
+
+// Advance moves the clock forward by d in steps, updating every thermostat
+// and streaming the thermostats that changed after each step.
+func (sim *Simulator) Advance(d time.Duration) {
+	for end := sim.now.Add(d); sim.now.Before(end); {
+		step := sim.Step
+		if step <= 0 {
+			step = time.Minute
+		}
+		if remaining := end.Sub(sim.now); remaining < step {
+			step = remaining
+		}
+		sim.step(step)
+	}
+}
+
+// step advances the clock by d and updates every thermostat. The clock is
+// changed with the server locked, as the server reads it while handling PUTs.
+func (sim *Simulator) step(d time.Duration) {
+	s := sim.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sim.now = sim.now.Add(d)
+
+	ids := make([]string, 0, len(s.devices.Thermostats))
+	for id := range s.devices.Thermostats {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		th := s.devices.Thermostats[id]
+		before := *th
+		sim.update(id, th, d.Hours())
+		// last_connection moves every step and alone is not a change
+		after := *th
+		after.LastConnection = before.LastConnection
+		if after != before {
+			s.publish(thermostatPath(id), th)
+		}
+	}
+}
+
+// update simulates a thermostat for hours.
+func (sim *Simulator) update(id string, th *device.Thermostat, hours float64) {
+	m, ok := sim.models[id]
+	if !ok {
+		m = DefaultModel
+	}
+	st, ok := sim.states[id]
+	if !ok {
+		st = &simState{ambientC: th.AmbientTemperatureC, humidity: float64(th.Humidity)}
+		if st.ambientC == 0 && th.AmbientTemperatureF != 0 {
+			st.ambientC = float64(th.AmbientTemperatureF-32) * 5 / 9
+		}
+		sim.states[id] = st
+	}
+
+	if th.FanTimerActive && !th.FanTimerTimeout.IsZero() && !sim.now.Before(th.FanTimerTimeout) {
+		th.FanTimerActive = false
+		th.FanTimerTimeout = time.Time{}
+	}
+
+	th.HvacState = hvacState(th, st.ambientC)
+	switch th.HvacState {
+	case device.HvacStateHeating:
+		st.ambientC += m.HeatRate * hours
+	case device.HvacStateCooling:
+		st.ambientC -= m.CoolRate * hours
+		st.humidity = math.Max(st.humidity-m.Dehumidify*hours, 0)
+	}
+	st.ambientC += (sim.Outside - st.ambientC) * m.Leak * hours
+	if th.HvacState != device.HvacStateCooling {
+		st.humidity += (m.Humidity - st.humidity) * math.Min(m.Leak*hours, 1)
+	}
+
+	th.AmbientTemperatureC = math.Round(st.ambientC*2) / 2
+	th.AmbientTemperatureF = int(math.Round(st.ambientC*9/5 + 32))
+	th.Humidity = int(math.Round(st.humidity))
+	th.TimeToTarget = timeToTarget(th, st.ambientC, m, sim.Outside)
+	th.LastConnection = sim.now
+	th.IsOnline = true
+}
+
+// hvacState returns whether the thermostat calls for heating or cooling at the
+// ambient temperature. Heating and cooling continue until the target is reached
+// and start again once the ambient temperature is past the target by more than
+// the hysteresis.
+func hvacState(th *device.Thermostat, ambientC float64) device.HvacState {
+	if th.IsUsingEmergencyHeat {
+		return device.HvacStateHeating
+	}
+	low, high, ok := targetC(th)
+	if !ok {
+		return device.HvacStateOff
+	}
+	heat := th.HvacMode != device.HvacModeCool && low != 0
+	cool := th.HvacMode != device.HvacModeHeat && high != 0
+	switch {
+	case heat && th.HvacState == device.HvacStateHeating && ambientC < low,
+		heat && ambientC < low-hysteresis:
+		return device.HvacStateHeating
+	case cool && th.HvacState == device.HvacStateCooling && ambientC > high,
+		cool && ambientC > high+hysteresis:
+		return device.HvacStateCooling
+	}
+	return device.HvacStateOff
+}
+
+// targetC returns the temperatures, in Celsius, the thermostat heats to and
+// cools to in its mode.
+func targetC(th *device.Thermostat) (float64, float64, bool) {
+	switch th.HvacMode {
+	case device.HvacModeHeat:
+		return th.TargetTemperatureC, 0, true
+	case device.HvacModeCool:
+		return 0, th.TargetTemperatureC, true
+	case device.HvacModeHeatCool:
+		return th.TargetTemperatureLowC, th.TargetTemperatureHighC, true
+	case device.HvacModeEco:
+		return th.EcoTemperatureLowC, th.EcoTemperatureHighC, true
+	}
+	return 0, 0, false
+}
+
+// timeToTarget estimates the minutes until the target is reached in the format
+// of the Nest API: "~0" when idle, "<5", "~N" in steps of five minutes or
+// ">120".
+func timeToTarget(th *device.Thermostat, ambientC float64, m Model, outside float64) string {
+	low, high, _ := targetC(th)
+	var gap, rate float64
+	switch th.HvacState {
+	case device.HvacStateHeating:
+		gap, rate = low-ambientC, m.HeatRate+(outside-ambientC)*m.Leak
+	case device.HvacStateCooling:
+		gap, rate = ambientC-high, m.CoolRate-(outside-ambientC)*m.Leak
+	default:
+		return "~0"
+	}
+	if rate <= 0 {
+		return ">120"
+	}
+	minutes := gap / rate * 60
+	switch {
+	case minutes < 5:
+		return "<5"
+	case minutes > 120:
+		return ">120"
+	}
+	return fmt.Sprintf("~%d", int(math.Round(minutes/5))*5)
+}
@@ -0,0 +1,117 @@
+package nesttest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSimulation(t *testing.T) (*Server, *Simulator) {
+	s := NewServer()
+	s.AddThermostat(&device.Thermostat{
+		DeviceID:            "t1",
+		HvacMode:            device.HvacModeOff,
+		CanHeat:             true,
+		CanCool:             true,
+		HasFan:              true,
+		AmbientTemperatureC: 20,
+		Humidity:            50,
+	})
+	return s, NewSimulator(s, time.Date(2020, 1, 15, 6, 0, 0, 0, time.UTC))
+}
+
+func Test_SimulatorDrift(t *testing.T) {
+	s, sim := newSimulation(t)
+	defer s.Close()
+	sim.Outside = 0
+
+	sim.Advance(3 * time.Hour)
+	th, _ := s.Thermostat("t1")
+	assert.Equal(t, time.Date(2020, 1, 15, 9, 0, 0, 0, time.UTC), sim.Now())
+	assert.Equal(t, 15.0, th.AmbientTemperatureC, "the home should cool toward the outside temperature")
+	assert.Equal(t, device.HvacStateOff, th.HvacState)
+	assert.Equal(t, "~0", th.TimeToTarget)
+	assert.Equal(t, sim.Now(), th.LastConnection)
+}
+
+func Test_SimulatorHeating(t *testing.T) {
+	s, sim := newSimulation(t)
+	defer s.Close()
+	n, _ := s.NewClient()
+
+	assert.NoError(t, n.Thermostats.SetHVACMode("t1", device.HvacModeHeat))
+	assert.NoError(t, n.Thermostats.SetTargetTemperature("t1", device.Celsius, 22))
+
+	sim.Advance(time.Minute)
+	th, _ := s.Thermostat("t1")
+	assert.Equal(t, device.HvacStateHeating, th.HvacState)
+	assert.Equal(t, "~120", th.TimeToTarget)
+
+	sim.Advance(2 * time.Hour)
+	th, _ = s.Thermostat("t1")
+	assert.InDelta(t, 22, th.AmbientTemperatureC, 0.5, "heating should hold the target")
+}
+
+func Test_SimulatorCooling(t *testing.T) {
+	s, sim := newSimulation(t)
+	defer s.Close()
+	sim.Outside = 28
+	n, _ := s.NewClient()
+
+	assert.NoError(t, n.Thermostats.SetHVACMode("t1", device.HvacModeHeatCool))
+	assert.NoError(t, n.Thermostats.SetTargetTemperatureRange("t1", device.Celsius, 16, 19))
+
+	sim.Advance(time.Hour)
+	th, _ := s.Thermostat("t1")
+	assert.Equal(t, device.HvacStateCooling, th.HvacState)
+	assert.True(t, th.Humidity < 50, "cooling should dehumidify")
+	assert.True(t, th.AmbientTemperatureC <= 19.5)
+}
+
+func Test_SimulatorFanTimer(t *testing.T) {
+	s, sim := newSimulation(t)
+	defer s.Close()
+
+	// the server's clock follows the simulator
+	req, _ := http.NewRequest(http.MethodPut, s.URL+"/devices/thermostats/t1", strings.NewReader(`{"fan_timer_duration": 30, "fan_timer_active": true}`))
+	resp, err := s.Client().Do(req)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	th, _ := s.Thermostat("t1")
+	assert.Equal(t, sim.Now().Add(30*time.Minute), th.FanTimerTimeout)
+
+	sim.Advance(29 * time.Minute)
+	th, _ = s.Thermostat("t1")
+	assert.True(t, th.FanTimerActive)
+	sim.Advance(time.Minute)
+	th, _ = s.Thermostat("t1")
+	assert.False(t, th.FanTimerActive)
+}
+
+func Test_SimulatorSteadyState(t *testing.T) {
+	s, sim := newSimulation(t)
+	defer s.Close()
+	sim.Outside = 20
+	sim.SetModel("t1", Model{Leak: 0.1, Humidity: 50})
+	sim.Step = 0
+
+	sim.Advance(time.Minute)
+	sub := &subscriber{notify: make(chan struct{}, 1)}
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	sim.Advance(10 * time.Minute)
+	assert.Equal(t, time.Date(2020, 1, 15, 6, 11, 0, 0, time.UTC), sim.Now(), "a step of zero should default to one minute")
+	th, _ := s.Thermostat("t1")
+	assert.Equal(t, sim.Now(), th.LastConnection)
+	s.mu.Lock()
+	assert.Empty(t, sub.queue, "thermostats that did not change should not be streamed")
+	s.mu.Unlock()
+}
@@ -0,0 +1,141 @@
+package nesttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// subscriber is an open event stream. Events are queued so publishing never
+// blocks on a slow reader.
+type subscriber struct {
+	path   string
+	queue  [][]byte
+	notify chan struct{}
+}
+
+// matches reports whether a change at path is streamed to the subscriber.
+func (sub *subscriber) matches(path string) bool {
+	return sub.path == "" || path == sub.path || strings.HasPrefix(path, sub.path+"/")
+}
+
+func (sub *subscriber) push(event []byte) {
+	sub.queue = append(sub.queue, event)
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// publish queues a put event for a change at path. It must be called with
+// s.mu held.
+func (s *Server) publish(path string, v interface{}) {
+	if len(s.subscribers) == 0 {
+		return
+	}
+	event, err := putEvent(path, v)
+	if err != nil {
+		return
+	}
+	for sub := range s.subscribers {
+		if sub.matches(path) {
+			sub.push(event)
+		}
+	}
+}
+
+// putEvent formats a put event. Every event carries a single device, as the
+// Nest API does for device streams, so streamed events can be decoded with
+// nest.Event.GetEvent.
+func putEvent(path string, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Path string      `json:"path"`
+		Data interface{} `json:"data"`
+	}{path, v})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("event: put\ndata: %s\n\n", data)), nil
+}
+
+// stream serves an event stream for path. The current state of every device
+// under path is sent first, followed by its changes.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, path string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, *apiError("Internal error", "streaming unsupported"))
+		return
+	}
+
+	s.mu.Lock()
+	if _, found := s.lookup(path); !found {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, notFound(path))
+		return
+	}
+	sub := &subscriber{path: path, notify: make(chan struct{}, 1)}
+	for _, d := range s.snapshot() {
+		if sub.matches(d.path) {
+			if event, err := putEvent(d.path, d.value); err == nil {
+				sub.push(event)
+			}
+		}
+	}
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-sub.notify:
+		}
+		s.mu.Lock()
+		queue := sub.queue
+		sub.queue = nil
+		s.mu.Unlock()
+		for _, event := range queue {
+			if _, err := w.Write(event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+type devicePath struct {
+	path  string
+	value interface{}
+}
+
+// snapshot returns every device with its path, ordered by path. It must be
+// called with s.mu held.
+func (s *Server) snapshot() []devicePath {
+	var devices []devicePath
+	for id, th := range s.devices.Thermostats {
+		devices = append(devices, devicePath{thermostatPath(id), th})
+	}
+	for id, sa := range s.devices.SmokeCoAlarms {
+		devices = append(devices, devicePath{smokeCoAlarmPath(id), sa})
+	}
+	for id, camera := range s.devices.Cameras {
+		devices = append(devices, devicePath{cameraPath(id), camera})
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].path < devices[j].path })
+	return devices
+}
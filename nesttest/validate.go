@@ -0,0 +1,175 @@
+package nesttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+	"github.com/jtsiros/nest/internal/rules"
+)
+
+const errorsURL = "https://developers.nest.com/documentation/cloud/error-messages"
+
+func apiError(err, message string) *nest.Error {
+	return &nest.Error{Err: err, Type: errorsURL, Message: message}
+}
+
+func notFound(path string) nest.Error {
+	return nest.Error{Err: "not found", Type: errorsURL, Message: "not found", Instance: path}
+}
+
+func invalidContent() nest.Error {
+	return *apiError("Invalid content sent", "Invalid content sent")
+}
+
+func notWritable(field string) *nest.Error {
+	return apiError("Not writable", fmt.Sprintf("Field '%s' is not writable", field))
+}
+
+func invalidValue(field string) *nest.Error {
+	return ruleError(rules.InvalidValue(field))
+}
+
+// ruleError converts a write rejected by the shared validation rules to the
+// error the API returns.
+func ruleError(err *rules.Error) *nest.Error {
+	if err == nil {
+		return nil
+	}
+	return apiError(err.Err, err.Message)
+}
+
+// applyThermostat validates and applies the values of a PUT to a thermostat.
+// Temperatures written in one scale are also converted to the other, and
+// starting the fan timer sets its timeout relative to now.
+func applyThermostat(th *device.Thermostat, values map[string]json.RawMessage, now time.Time) *nest.Error {
+	var (
+		target, low, high  *float64
+		targetScale, scale device.TemperatureScale
+	)
+	for _, field := range sortedFields(values) {
+		raw := values[field]
+		switch field {
+		case "target_temperature_f", "target_temperature_c",
+			"target_temperature_low_f", "target_temperature_low_c",
+			"target_temperature_high_f", "target_temperature_high_c":
+			var t float64
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return invalidValue(field)
+			}
+			s := device.Celsius
+			if strings.HasSuffix(field, "_f") {
+				s = device.Fahrenheit
+			}
+			if targetScale != "" && targetScale != s {
+				return apiError("Invalid content sent", "Cannot set temperatures in both scales")
+			}
+			targetScale = s
+			switch field[:len(field)-2] {
+			case "target_temperature":
+				target = &t
+			case "target_temperature_low":
+				low = &t
+			case "target_temperature_high":
+				high = &t
+			}
+		case "hvac_mode":
+			var mode device.HvacMode
+			if err := json.Unmarshal(raw, &mode); err != nil {
+				return invalidValue(field)
+			}
+			if err := rules.SetHvacMode(th, mode); err != nil {
+				return ruleError(err)
+			}
+		case "fan_timer_active":
+			var active bool
+			if err := json.Unmarshal(raw, &active); err != nil {
+				return invalidValue(field)
+			}
+			if active && !th.HasFan {
+				return apiError("No fan", "Thermostat has no fan")
+			}
+			th.FanTimerActive = active
+		case "fan_timer_duration":
+			var d int
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return invalidValue(field)
+			}
+			if err := rules.CheckFanTimerDuration(d); err != nil {
+				return ruleError(err)
+			}
+			th.FanTimerDuration = d
+		case "label":
+			if err := json.Unmarshal(raw, &th.Label); err != nil {
+				return invalidValue(field)
+			}
+		case "temperature_scale":
+			if err := json.Unmarshal(raw, &scale); err != nil {
+				return invalidValue(field)
+			}
+			th.TemperatureScale = scale
+		default:
+			return notWritable(field)
+		}
+	}
+
+	if th.FanTimerActive && values["fan_timer_active"] != nil {
+		d := th.FanTimerDuration
+		if d == 0 {
+			d = 15
+		}
+		th.FanTimerTimeout = now.Add(time.Duration(d) * time.Minute)
+	}
+	if target != nil {
+		if err := rules.SetTargetTemperature(th, targetScale, *target); err != nil {
+			return ruleError(err)
+		}
+	}
+	if low != nil || high != nil {
+		if err := rules.SetTargetTemperatureRange(th, targetScale, low, high); err != nil {
+			return ruleError(err)
+		}
+	}
+	return nil
+}
+
+// applyCamera validates and applies the values of a PUT to a camera.
+func applyCamera(camera *device.Camera, values map[string]json.RawMessage) *nest.Error {
+	for _, field := range sortedFields(values) {
+		if field != "is_streaming" {
+			return notWritable(field)
+		}
+		if err := json.Unmarshal(values[field], &camera.IsStreaming); err != nil {
+			return invalidValue(field)
+		}
+	}
+	return nil
+}
+
+// applyStructure validates and applies the values of a PUT to a structure.
+func applyStructure(st *device.Structure, values map[string]json.RawMessage) *nest.Error {
+	for _, field := range sortedFields(values) {
+		if field != "away" {
+			return notWritable(field)
+		}
+		if err := json.Unmarshal(values[field], &st.Away); err != nil {
+			return invalidValue(field)
+		}
+	}
+	return nil
+}
+
+// sortedFields returns the fields of a PUT in a stable order, so the error
+// reported for an invalid request does not depend on map iteration.
+func sortedFields(values map[string]json.RawMessage) []string {
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"strings"
+
+	"github.com/jtsiros/nest"
+)
+
+// CameraMessage describes the start or end of a camera event.
+func CameraMessage(n nest.CameraNotification) Message {
+	var activity []string
+	if n.Event.HasPerson {
+		activity = append(activity, "person")
+	}
+	if n.Event.HasMotion {
+		activity = append(activity, "motion")
+	}
+	if n.Event.HasSound {
+		activity = append(activity, "sound")
+	}
+	fields := map[string]string{
+		"kind":     string(n.Kind),
+		"activity": strings.Join(activity, ", "),
+	}
+	name := n.DeviceID
+	if n.Camera != nil {
+		if n.Camera.NameLong != "" {
+			name = n.Camera.NameLong
+		}
+		if zones := n.Camera.EventZoneNames(); len(zones) > 0 {
+			fields["zones"] = strings.Join(zones, ", ")
+		}
+	}
+	if n.Event.WebURL != "" {
+		fields["url"] = n.Event.WebURL
+	}
+	t := n.Event.StartTime
+	if n.Kind == nest.CameraEventEnded {
+		t = n.Event.EndTime
+	}
+	return Message{
+		Title:    "camera event " + string(n.Kind) + ": " + name,
+		Body:     n.String(),
+		Severity: Info,
+		DeviceID: n.DeviceID,
+		Time:     t,
+		Fields:   fields,
+	}
+}
+
+// EmergencyHeatMessage describes a thermostat starting or stopping emergency
+// heat. Emergency heat turning on is a warning.
+func EmergencyHeatMessage(e nest.EmergencyHeatEvent) Message {
+	severity := Info
+	if e.Active {
+		severity = Warning
+	}
+	return Message{
+		Title:    e.String(),
+		Body:     e.String(),
+		Severity: severity,
+		DeviceID: e.DeviceID,
+		Time:     e.Time,
+	}
+}
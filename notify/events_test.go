@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CameraMessage(t *testing.T) {
+	start := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	camera := &device.Camera{
+		DeviceID:      "c1",
+		NameLong:      "Front Door",
+		ActivityZones: []*device.ActivityZone{{ID: 1, Name: "Driveway"}},
+	}
+	event := device.CameraEvent{HasPerson: true, HasMotion: true, StartTime: start, EndTime: start.Add(time.Minute), WebURL: "https://home.nest.com/e", ActivityZoneIDs: []string{"1"}}
+	camera.LastEvent = &event
+
+	m := CameraMessage(nest.CameraNotification{DeviceID: "c1", Kind: nest.CameraEventEnded, Event: event, Camera: camera})
+	assert.Equal(t, "camera event ended: Front Door", m.Title)
+	assert.Equal(t, start.Add(time.Minute), m.Time)
+	assert.Equal(t, map[string]string{"kind": "ended", "activity": "person, motion", "zones": "Driveway", "url": "https://home.nest.com/e"}, m.Fields)
+}
+
+func Test_EmergencyHeatMessage(t *testing.T) {
+	m := EmergencyHeatMessage(nest.EmergencyHeatEvent{DeviceID: "t1", Active: true})
+	assert.Equal(t, "emergency heat on: t1", m.Title)
+	assert.Equal(t, Warning, m.Severity)
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Exec runs a local command for every message. The message is written to the
+// command's standard input as JSON, and its title, body, severity and device
+// id are set in the NEST_TITLE, NEST_BODY, NEST_SEVERITY and NEST_DEVICE_ID
+// environment variables.
+type Exec struct {
+	Name string
+	Args []string
+	Env  []string
+}
+
+// NewExec creates a notifier that runs the named command with args.
+func NewExec(name string, args ...string) *Exec {
+	return &Exec{Name: name, Args: args}
+}
+
+// Notify runs the command and waits for it to exit. A non-zero exit status is
+// an error that includes the command's standard error.
+func (e *Exec) Notify(ctx context.Context, m Message) error {
+	input, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, e.Name, e.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(append(os.Environ(), e.Env...),
+		"NEST_TITLE="+m.Title,
+		"NEST_BODY="+m.Body,
+		"NEST_SEVERITY="+string(m.Severity),
+		"NEST_DEVICE_ID="+m.DeviceID,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %v: %s", e.Name, err, msg)
+		}
+		return fmt.Errorf("%s: %v", e.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Exec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	e := NewExec("sh", "-c", `cat > "$OUT" && echo "$NEST_SEVERITY $NEST_TITLE" >> "$OUT.env"`)
+	e.Env = []string{"OUT=" + out}
+	m := Message{Title: "emergency heat on: 123", Severity: Warning, DeviceID: "123"}
+	assert.NoError(t, e.Notify(context.Background(), m))
+
+	b, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	var got Message
+	assert.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, m, got)
+
+	env, err := ioutil.ReadFile(out + ".env")
+	assert.NoError(t, err)
+	assert.Equal(t, "warning emergency heat on: 123\n", string(env))
+}
+
+func Test_ExecFailure(t *testing.T) {
+	err := NewExec("sh", "-c", "echo boom >&2; exit 3").Notify(context.Background(), Message{})
+	assert.EqualError(t, err, "sh: exit status 3: boom")
+}
@@ -0,0 +1,85 @@
+// Package notify delivers messages about Nest devices through webhooks, email
+// or local commands. Camera and emergency heat events are turned into messages
+// here, and the alarm and health monitors send their alerts as messages, so the
+// same notifiers can be used from every stream handler.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity is the importance of a message.
+type Severity string
+
+// Message severities
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+// Message is a notification about a device. Fields holds additional details,
+// e.g. the alarm state or camera activity zones.
+type Message struct {
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	Severity Severity          `json:"severity"`
+	DeviceID string            `json:"device_id,omitempty"`
+	Time     time.Time         `json:"time"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+func (m Message) String() string {
+	return fmt.Sprintf("[%s] %s", m.Severity, m.Title)
+}
+
+// Text returns the body of the message followed by its fields, one per line,
+// in name order.
+func (m Message) Text() string {
+	var b strings.Builder
+	b.WriteString(m.Body)
+	names := make([]string, 0, len(m.Fields))
+	for name := range m.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i == 0 && b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "\n%s: %s", name, m.Fields[name])
+	}
+	return b.String()
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// NotifierFunc adapts a function to a Notifier.
+type NotifierFunc func(ctx context.Context, m Message) error
+
+// Notify calls f.
+func (f NotifierFunc) Notify(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// Multi sends every message to all of its notifiers. All notifiers are
+// attempted; the first error is returned.
+type Multi []Notifier
+
+// Notify sends m to all notifiers.
+func (mn Multi) Notify(ctx context.Context, m Message) error {
+	var firstErr error
+	for _, n := range mn {
+		if err := n.Notify(ctx, m); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
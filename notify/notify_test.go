@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MessageText(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		want string
+	}{
+		{"body only", Message{Body: "smoke alarm"}, "smoke alarm"},
+		{"fields sorted", Message{Body: "smoke alarm", Fields: map[string]string{"state": "warning", "kind": "smoke"}}, "smoke alarm\n\nkind: smoke\nstate: warning"},
+		{"fields only", Message{Fields: map[string]string{"kind": "co"}}, "\nkind: co"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.m.Text())
+		})
+	}
+}
+
+func Test_Multi(t *testing.T) {
+	var sent []string
+	record := func(name string, err error) Notifier {
+		return NotifierFunc(func(ctx context.Context, m Message) error {
+			sent = append(sent, name+": "+m.Title)
+			return err
+		})
+	}
+	n := Multi{record("a", nil), record("b", errors.New("unreachable")), record("c", errors.New("timeout"))}
+
+	err := n.Notify(context.Background(), Message{Title: "hello"})
+	assert.EqualError(t, err, "unreachable")
+	assert.Equal(t, []string{"a: hello", "b: hello", "c: hello"}, sent)
+}
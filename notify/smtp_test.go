@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SMTP(t *testing.T) {
+	var addr, from string
+	var to []string
+	var msg []byte
+	s := NewSMTP("mail.example.com:587", nil, "nest@example.com", "a@example.com", "b@example.com")
+	s.send = func(ctx context.Context, a string, auth smtp.Auth, f string, t []string, m []byte) error {
+		addr, from, to, msg = a, f, t, m
+		return nil
+	}
+
+	err := s.Notify(context.Background(), Message{
+		Title:    "smoke alarm emergency:\r\nBcc: x@example.com",
+		Body:     "smoke alarm emergency",
+		Severity: Critical,
+		Time:     time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC),
+		Fields:   map[string]string{"kind": "smoke"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "mail.example.com:587", addr)
+	assert.Equal(t, "nest@example.com", from)
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, to)
+	assert.Equal(t, "From: nest@example.com\r\n"+
+		"To: a@example.com, b@example.com\r\n"+
+		"Subject: [critical] smoke alarm emergency: Bcc: x@example.com\r\n"+
+		"Date: Tue, 24 Mar 2020 11:00:00 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"smoke alarm emergency\r\n\r\nkind: smoke\r\n", string(msg))
+}
+
+func Test_SMTPNoRecipients(t *testing.T) {
+	err := NewSMTP("mail.example.com:587", nil, "nest@example.com").Notify(context.Background(), Message{})
+	assert.EqualError(t, err, "smtp: no recipients")
+}
+
+func Test_SMTPSubjectEncoding(t *testing.T) {
+	var msg []byte
+	s := NewSMTP("mail.example.com:587", nil, "nest@example.com", "a@example.com")
+	s.send = func(ctx context.Context, a string, auth smtp.Auth, f string, t []string, m []byte) error {
+		msg = m
+		return nil
+	}
+
+	assert.NoError(t, s.Notify(context.Background(), Message{Title: "offline: Küche", Severity: Warning}))
+	assert.Contains(t, string(msg), "Subject: =?utf-8?q?[warning]_offline:_K=C3=BCche?=\r\n")
+}
+
+func Test_SMTPContext(t *testing.T) {
+	// a server that accepts connections but never greets the client
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		<-done
+		conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = NewSMTP(l.Addr().String(), nil, "nest@example.com", "a@example.com").Notify(ctx, Message{Title: "offline"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// Webhook posts messages to a URL. Without a Template the message is sent as
+// JSON; otherwise the template is executed with the Message to render the body,
+// e.g. to match the payload of a chat service. When the content type is JSON,
+// the text of the message is escaped for use inside JSON strings, so a
+// template such as {"text":"{{.Title}}"} stays valid whatever the title holds.
+type Webhook struct {
+	URL         string
+	Template    *template.Template
+	ContentType string
+	Header      http.Header
+	Client      *http.Client
+}
+
+// NewWebhook creates a webhook that posts messages as JSON to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, ContentType: "application/json"}
+}
+
+// NewTemplateWebhook creates a webhook that posts the output of the text
+// template tmpl, sent with the given content type.
+func NewTemplateWebhook(url, contentType, tmpl string) (*Webhook, error) {
+	t, err := template.New("webhook").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	return &Webhook{URL: url, Template: t, ContentType: contentType}, nil
+}
+
+// Notify posts m to the webhook URL. Responses other than 2xx are errors.
+func (w *Webhook) Notify(ctx context.Context, m Message) error {
+	var body bytes.Buffer
+	if w.Template != nil {
+		data := m
+		if isJSON(w.ContentType) {
+			data = jsonEscaped(m)
+		}
+		if err := w.Template.Execute(&body, data); err != nil {
+			return err
+		}
+	} else if err := json.NewEncoder(&body).Encode(m); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, &body)
+	if err != nil {
+		return err
+	}
+	for name, values := range w.Header {
+		req.Header[name] = values
+	}
+	if w.ContentType != "" {
+		req.Header.Set("Content-Type", w.ContentType)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// jsonEscaped returns a copy of m with its text escaped as the contents of
+// JSON strings.
+func jsonEscaped(m Message) Message {
+	m.Title = jsonString(m.Title)
+	m.Body = jsonString(m.Body)
+	m.Severity = Severity(jsonString(string(m.Severity)))
+	m.DeviceID = jsonString(m.DeviceID)
+	if m.Fields != nil {
+		fields := make(map[string]string, len(m.Fields))
+		for name, value := range m.Fields {
+			fields[jsonString(name)] = jsonString(value)
+		}
+		m.Fields = fields
+	}
+	return m
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Webhook(t *testing.T) {
+	var body []byte
+	var contentType, token string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		token = r.Header.Get("X-Token")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	m := Message{
+		Title:    "co alarm warning: Hallway",
+		Severity: Warning,
+		DeviceID: "123",
+		Time:     time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("json", func(t *testing.T) {
+		w := NewWebhook(ts.URL)
+		w.Header = http.Header{"X-Token": {"secret"}}
+		assert.NoError(t, w.Notify(context.Background(), m))
+		assert.Equal(t, "application/json", contentType)
+		assert.Equal(t, "secret", token)
+
+		var got Message
+		assert.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, m, got)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		w, err := NewTemplateWebhook(ts.URL, "application/json", `{"text":"{{.Severity}}: {{.Title}}"}`)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Notify(context.Background(), m))
+		assert.Equal(t, `{"text":"warning: co alarm warning: Hallway"}`, string(body))
+	})
+
+	t.Run("template escaping", func(t *testing.T) {
+		w, err := NewTemplateWebhook(ts.URL, "application/json; charset=utf-8", `{"text":"{{.Title}}","zone":"{{index .Fields "zones"}}"}`)
+		assert.NoError(t, err)
+		quoted := m
+		quoted.Title = "co alarm warning: \"Kids\" Room\n"
+		quoted.Fields = map[string]string{"zones": `Back\Yard`}
+		assert.NoError(t, w.Notify(context.Background(), quoted))
+
+		var got map[string]string
+		assert.NoError(t, json.Unmarshal(body, &got))
+		assert.Equal(t, map[string]string{"text": quoted.Title, "zone": `Back\Yard`}, got)
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		_, err := NewTemplateWebhook(ts.URL, "text/plain", "{{.Title")
+		assert.Error(t, err)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		err := NewWebhook(ts.URL+"/fail").Notify(context.Background(), m)
+		assert.EqualError(t, err, "webhook: unexpected status code 500")
+	})
+}
@@ -0,0 +1,123 @@
+package nest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// DefaultMinRampInterval is the minimum time between writes made while ramping
+// a target temperature when Client.MinRampInterval is not set. Nest blocks
+// clients that write to a device too often, so a ramp with more steps than fit
+// in its duration uses larger steps instead.
+const DefaultMinRampInterval = time.Minute
+
+// rampRetries is the number of times a ramp step is retried after the API
+// responds with 429 Too Many Requests.
+const rampRetries = 3
+
+// RampTargetTemperature gradually changes the target temperature from its
+// current value to target over the given duration, one degree at a time. The
+// thermostat must be in heat or cool mode. RampTargetTemperature blocks until
+// the target is reached or ctx is done.
+//
+func (svc *ThermostatService) RampTargetTemperature(ctx context.Context, deviceid string, scale device.TemperatureScale, target int, over time.Duration) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	th, err := svc.Get(deviceid)
+	if err != nil {
+		return err
+	}
+	if th.HvacMode != device.HvacModeHeat && th.HvacMode != device.HvacModeCool {
+		return fmt.Errorf("cannot ramp target temperature in %s mode", th.HvacMode)
+	}
+
+	steps := rampSteps(currentTarget(th, scale), target, over, svc.client.minRampInterval())
+	if len(steps) == 0 {
+		return nil
+	}
+	interval := over / time.Duration(len(steps))
+	for _, step := range steps {
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+		if err := svc.setTargetWithRetry(ctx, deviceid, scale, step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *ThermostatService) setTargetWithRetry(ctx context.Context, deviceid string, scale device.TemperatureScale, target int) error {
+	var err error
+	for i := 0; i <= rampRetries; i++ {
+		err = svc.SetTargetTemperature(deviceid, scale, target)
+		if apiErr, ok := err.(Error); !ok || apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		if i == rampRetries {
+			break
+		}
+		if err := sleep(ctx, svc.client.minRampInterval()); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// rampSteps returns the intermediate targets between current and target. The
+// number of steps is limited so that steps are at least min apart.
+func rampSteps(current, target int, over, min time.Duration) []int {
+	diff := target - current
+	n := diff
+	if n < 0 {
+		n = -n
+	}
+	if n == 0 {
+		return nil
+	}
+	if max := int(over / min); n > max {
+		n = max
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	steps := make([]int, n)
+	for i := 1; i <= n; i++ {
+		steps[i-1] = current + int(math.Round(float64(diff*i)/float64(n)))
+	}
+	return steps
+}
+
+// minRampInterval returns the minimum time between ramp writes of the client.
+func (nest *Client) minRampInterval() time.Duration {
+	if nest.MinRampInterval > 0 {
+		return nest.MinRampInterval
+	}
+	return DefaultMinRampInterval
+}
+
+// currentTarget returns the target temperature of the thermostat in the given scale.
+func currentTarget(th *device.Thermostat, scale device.TemperatureScale) int {
+	if scale == device.Celsius {
+		return int(math.Round(th.TargetTemperatureC))
+	}
+	return th.TargetTemperatureF
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package nest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rampSteps(t *testing.T) {
+	tt := []struct {
+		current, target int
+		over            time.Duration
+		expected        []int
+	}{
+		{68, 72, time.Hour, []int{69, 70, 71, 72}},
+		{72, 68, time.Hour, []int{71, 70, 69, 68}},
+		{60, 70, 5 * time.Minute, []int{62, 64, 66, 68, 70}},
+		{60, 70, 0, []int{70}},
+		{70, 70, time.Hour, nil},
+	}
+
+	for _, tc := range tt {
+		assert.Equal(t, tc.expected, rampSteps(tc.current, tc.target, tc.over, time.Minute))
+	}
+}
+
+func Test_RampTargetTemperature(t *testing.T) {
+	s, ts := newThermostatServer(map[string]interface{}{"device_id": "123", "hvac_mode": "heat", "target_temperature_f": 68})
+	s.fail = 1
+	c := newTestClientWithServer(ts)
+	c.MinRampInterval = time.Millisecond
+	svc := NewThermostatService(c)
+
+	assert.NoError(t, svc.RampTargetTemperature(context.Background(), "123", F, 71, 3*time.Millisecond))
+	assert.Equal(t, []string{"target_temperature_f=69", "target_temperature_f=70", "target_temperature_f=71"}, s.Writes())
+}
+
+func Test_RampTargetTemperatureErrors(t *testing.T) {
+	_, ts := newThermostatServer(map[string]interface{}{"device_id": "123", "hvac_mode": "heat-cool"})
+	svc := NewThermostatService(newTestClientWithServer(ts))
+	assert.EqualError(t, svc.RampTargetTemperature(context.Background(), "123", F, 71, time.Hour), "cannot ramp target temperature in heat-cool mode")
+
+	_, ts = newThermostatServer(map[string]interface{}{"device_id": "123", "hvac_mode": "heat", "target_temperature_f": 68})
+	svc = NewThermostatService(newTestClientWithServer(ts))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, svc.RampTargetTemperature(ctx, "123", F, 71, time.Hour))
+}
+
+func Test_setTargetWithRetry(t *testing.T) {
+	s, ts := newThermostatServer(map[string]interface{}{"device_id": "123", "hvac_mode": "heat", "target_temperature_f": 68})
+	s.fail = rampRetries + 1
+	c := newTestClientWithServer(ts)
+	c.MinRampInterval = 100 * time.Millisecond
+	svc := NewThermostatService(c)
+
+	start := time.Now()
+	err := svc.setTargetWithRetry(context.Background(), "123", F, 70)
+	if assert.IsType(t, Error{}, err) {
+		assert.Equal(t, http.StatusTooManyRequests, err.(Error).StatusCode)
+	}
+	assert.True(t, time.Since(start) < time.Duration(rampRetries+1)*c.MinRampInterval, "there should be no backoff after the last attempt")
+}
@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// TimeOfDay is a wall clock time within a day, in the time zone of the structure
+// the thermostat belongs to. It is encoded as "HH:MM" in JSON.
+type TimeOfDay struct {
+	Hour   int
+	Minute int
+}
+
+// Clock returns the TimeOfDay for the given hour and minute.
+func Clock(hour, minute int) TimeOfDay {
+	return TimeOfDay{Hour: hour, Minute: minute}
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// Validate returns an error if t is not a valid wall clock time.
+func (t TimeOfDay) Validate() error {
+	if t.Hour < 0 || t.Hour > 23 || t.Minute < 0 || t.Minute > 59 {
+		return fmt.Errorf("invalid time of day: %s", t)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *TimeOfDay) UnmarshalText(b []byte) error {
+	var v TimeOfDay
+	if _, err := fmt.Sscanf(string(b), "%d:%d", &v.Hour, &v.Minute); err != nil {
+		return fmt.Errorf("invalid time of day %q: %v", string(b), err)
+	}
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+func (t TimeOfDay) minutes() int {
+	return t.Hour*60 + t.Minute
+}
+
+// Setpoint is a schedule entry that takes effect at a time of day and stays in
+// effect until the next setpoint. Mode is optional; when empty the thermostat's
+// current mode is kept. Target is used in heat or cool mode, Low and High in
+// heat-cool mode.
+type Setpoint struct {
+	At     TimeOfDay       `json:"at"`
+	Mode   device.HvacMode `json:"mode,omitempty"`
+	Target int             `json:"target,omitempty"`
+	Low    int             `json:"low,omitempty"`
+	High   int             `json:"high,omitempty"`
+}
+
+// Validate returns an error if the setpoint cannot be applied.
+func (sp Setpoint) Validate() error {
+	if err := sp.At.Validate(); err != nil {
+		return err
+	}
+	if sp.Mode != "" {
+		if err := sp.Mode.Validate(); err != nil {
+			return err
+		}
+	}
+	if sp.Low != 0 || sp.High != 0 {
+		if sp.Low >= sp.High {
+			return errors.New("low value must be less than high value")
+		}
+	}
+	switch sp.Mode {
+	case device.HvacModeHeat, device.HvacModeCool:
+		if sp.Target == 0 {
+			return fmt.Errorf("setpoint at %s: target is required in %s mode", sp.At, sp.Mode)
+		}
+	case device.HvacModeHeatCool:
+		if sp.Low == 0 && sp.High == 0 {
+			return fmt.Errorf("setpoint at %s: low and high are required in %s mode", sp.At, sp.Mode)
+		}
+	case "":
+		if sp.Target == 0 && sp.Low == 0 && sp.High == 0 {
+			return fmt.Errorf("setpoint at %s: a mode, target or range is required", sp.At)
+		}
+	}
+	return nil
+}
+
+// Schedule is a weekly schedule for a single thermostat. Temperatures are
+// expressed in Scale.
+type Schedule struct {
+	DeviceID string                      `json:"device_id"`
+	Scale    device.TemperatureScale     `json:"scale"`
+	Days     map[time.Weekday][]Setpoint `json:"days"`
+}
+
+// Validate returns an error if the schedule is incomplete or contains invalid setpoints.
+func (s Schedule) Validate() error {
+	if s.DeviceID == "" {
+		return errors.New("schedule requires a device id")
+	}
+	if err := s.Scale.Validate(); err != nil {
+		return err
+	}
+	n := 0
+	for day, setpoints := range s.Days {
+		if day < time.Sunday || day > time.Saturday {
+			return fmt.Errorf("invalid weekday: %d", day)
+		}
+		for _, sp := range setpoints {
+			if err := sp.Validate(); err != nil {
+				return fmt.Errorf("%s: %v", day, err)
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		return errors.New("schedule has no setpoints")
+	}
+	return nil
+}
+
+// Active returns the setpoint in effect at t along with the instant it took
+// effect. Setpoints are evaluated in the location of t. When no setpoint has
+// been reached yet today, the last setpoint of the most recent scheduled day
+// is in effect. ok is false when the schedule has no setpoints.
+func (s Schedule) Active(t time.Time) (sp Setpoint, since time.Time, ok bool) {
+	y, m, d := t.Date()
+	for back := 0; back <= 7; back++ {
+		day := time.Date(y, m, d-back, 0, 0, 0, 0, t.Location())
+		setpoints := sorted(s.Days[day.Weekday()])
+		for i := len(setpoints) - 1; i >= 0; i-- {
+			start := time.Date(day.Year(), day.Month(), day.Day(), setpoints[i].At.Hour, setpoints[i].At.Minute, 0, 0, t.Location())
+			if !start.After(t) {
+				return setpoints[i], start, true
+			}
+		}
+	}
+	return Setpoint{}, time.Time{}, false
+}
+
+func sorted(setpoints []Setpoint) []Setpoint {
+	s := make([]Setpoint, len(setpoints))
+	copy(s, setpoints)
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].At.minutes() < s[j].At.minutes()
+	})
+	return s
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func weekdays(setpoints ...Setpoint) map[time.Weekday][]Setpoint {
+	days := map[time.Weekday][]Setpoint{}
+	for d := time.Monday; d <= time.Friday; d++ {
+		days[d] = setpoints
+	}
+	return days
+}
+
+func Test_Active(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Los_Angeles")
+	s := Schedule{
+		DeviceID: "123",
+		Scale:    device.Fahrenheit,
+		Days: weekdays(
+			Setpoint{At: Clock(22, 0), Target: 62},
+			Setpoint{At: Clock(6, 30), Mode: device.HvacModeHeat, Target: 68},
+		),
+	}
+
+	tt := []struct {
+		now    time.Time
+		target int
+		since  time.Time
+	}{
+		// Tuesday morning, after the 06:30 setpoint
+		{time.Date(2020, 3, 24, 7, 0, 0, 0, loc), 68, time.Date(2020, 3, 24, 6, 30, 0, 0, loc)},
+		// Tuesday before 06:30 picks up Monday's 22:00 setpoint
+		{time.Date(2020, 3, 24, 5, 0, 0, 0, loc), 62, time.Date(2020, 3, 23, 22, 0, 0, 0, loc)},
+		// Sunday has no setpoints so Friday's last setpoint is in effect
+		{time.Date(2020, 3, 29, 12, 0, 0, 0, loc), 62, time.Date(2020, 3, 27, 22, 0, 0, 0, loc)},
+	}
+
+	for _, tc := range tt {
+		sp, since, ok := s.Active(tc.now)
+		assert.True(t, ok)
+		assert.Equal(t, tc.target, sp.Target)
+		assert.True(t, tc.since.Equal(since), "expected %v, got %v", tc.since, since)
+	}
+
+	_, _, ok := Schedule{}.Active(time.Now())
+	assert.False(t, ok)
+}
+
+func Test_ScheduleValidate(t *testing.T) {
+	tt := []struct {
+		s   Schedule
+		err string
+	}{
+		{Schedule{DeviceID: "123", Scale: device.Celsius, Days: weekdays(Setpoint{At: Clock(7, 0), Target: 20})}, ""},
+		{Schedule{Scale: device.Celsius}, "schedule requires a device id"},
+		{Schedule{DeviceID: "123", Scale: "K"}, `invalid temperature scale: "K"`},
+		{Schedule{DeviceID: "123", Scale: device.Celsius}, "schedule has no setpoints"},
+		{Schedule{DeviceID: "123", Scale: device.Celsius, Days: map[time.Weekday][]Setpoint{time.Monday: {{At: Clock(25, 0), Target: 20}}}}, "Monday: invalid time of day: 25:00"},
+		{Schedule{DeviceID: "123", Scale: device.Celsius, Days: map[time.Weekday][]Setpoint{time.Monday: {{At: Clock(7, 0), Mode: device.HvacModeHeatCool}}}}, "Monday: setpoint at 07:00: low and high are required in heat-cool mode"},
+		{Schedule{DeviceID: "123", Scale: device.Celsius, Days: map[time.Weekday][]Setpoint{time.Monday: {{At: Clock(7, 0), Low: 22, High: 20}}}}, "Monday: low value must be less than high value"},
+		{Schedule{DeviceID: "123", Scale: device.Celsius, Days: map[time.Weekday][]Setpoint{time.Monday: {{At: Clock(7, 0)}}}}, "Monday: setpoint at 07:00: a mode, target or range is required"},
+	}
+
+	for _, tc := range tt {
+		err := tc.s.Validate()
+		if tc.err == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func Test_ScheduleJSON(t *testing.T) {
+	body := `{"device_id":"123","scale":"F","days":{"1":[{"at":"06:30","mode":"heat-cool","low":65,"high":75}]}}`
+
+	var s Schedule
+	assert.NoError(t, json.Unmarshal([]byte(body), &s))
+	assert.Equal(t, Clock(6, 30), s.Days[time.Monday][0].At)
+	assert.Equal(t, device.HvacModeHeatCool, s.Days[time.Monday][0].Mode)
+
+	b, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, body, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"days":{"1":[{"at":"6am"}]}}`), &s))
+}
@@ -0,0 +1,151 @@
+// Package scheduler applies weekly thermostat schedules locally. The Nest REST
+// API has no schedule endpoints, so the scheduler periodically evaluates each
+// schedule in the time zone of the thermostat's structure and writes the active
+// setpoint through the ThermostatService.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest/backend"
+	"github.com/jtsiros/nest/device"
+)
+
+// Scheduler applies weekly schedules to thermostats. A setpoint is applied once
+// when it takes effect; manual changes made afterwards are left alone until
+// the next setpoint. Setpoints are skipped while the structure is away or the
+// thermostat is in eco mode, and applied once the structure is home again.
+type Scheduler struct {
+	thermostats backend.Thermostats
+	structures  backend.Structures
+	store       Store
+
+	mu        sync.Mutex
+	schedules map[string]Schedule
+	state     *State
+	now       func() time.Time
+}
+
+// New creates a scheduler and loads its previously persisted state from store.
+// The services of any backend can be used, e.g. those of a nest.Client.
+func New(thermostats backend.Thermostats, structures backend.Structures, store Store) (*Scheduler, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load scheduler state: %v", err)
+	}
+	return &Scheduler{
+		thermostats: thermostats,
+		structures:  structures,
+		store:       store,
+		schedules:   map[string]Schedule{},
+		state:       state,
+		now:         time.Now,
+	}, nil
+}
+
+// Add adds or replaces the schedule for a thermostat.
+func (s *Scheduler) Add(schedule Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schedules[schedule.DeviceID] = schedule
+	return nil
+}
+
+// Remove removes the schedule for a thermostat.
+func (s *Scheduler) Remove(deviceid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.schedules, deviceid)
+}
+
+// Apply evaluates every schedule once and applies setpoints that have taken
+// effect since they were last applied. All schedules are evaluated even if
+// one fails; the first error is returned.
+func (s *Scheduler) Apply() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	now := s.now()
+	for id, schedule := range s.schedules {
+		if err := s.apply(schedule, now); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("device %s: %v", id, err)
+		}
+	}
+	return firstErr
+}
+
+// Run calls Apply every interval until ctx is done. Errors are logged and do
+// not stop the scheduler.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Apply(); err != nil {
+			log.Printf("scheduler: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) apply(schedule Schedule, now time.Time) error {
+	th, err := s.thermostats.Get(schedule.DeviceID)
+	if err != nil {
+		return err
+	}
+	st, err := s.structures.Get(th.StructureID)
+	if err != nil {
+		return err
+	}
+	loc, err := st.Location()
+	if err != nil {
+		return err
+	}
+
+	sp, since, ok := schedule.Active(now.In(loc))
+	if !ok {
+		return nil
+	}
+	if last, ok := s.state.Applied[schedule.DeviceID]; ok && last.Equal(since) {
+		return nil
+	}
+	if st.Away == device.AwayStateAway || th.HvacMode == device.HvacModeEco {
+		return nil
+	}
+
+	mode := th.HvacMode
+	if sp.Mode != "" && sp.Mode != th.HvacMode {
+		if err := s.thermostats.SetHVACMode(schedule.DeviceID, sp.Mode); err != nil {
+			return err
+		}
+		mode = sp.Mode
+	}
+
+	// A setpoint whose temperatures cannot be written in the current mode is
+	// left unrecorded so it is retried, e.g. once the mode is changed back.
+	switch {
+	case mode == device.HvacModeHeatCool && (sp.Low != 0 || sp.High != 0):
+		err = s.thermostats.SetTargetTemperatureRange(schedule.DeviceID, schedule.Scale, sp.Low, sp.High)
+	case (mode == device.HvacModeHeat || mode == device.HvacModeCool) && sp.Target != 0:
+		err = s.thermostats.SetTargetTemperature(schedule.DeviceID, schedule.Scale, sp.Target)
+	case sp.Target != 0 || sp.Low != 0 || sp.High != 0:
+		err = fmt.Errorf("setpoint at %s cannot be applied in %s mode", sp.At, mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.state.Applied[schedule.DeviceID] = since
+	return s.store.Save(s.state)
+}
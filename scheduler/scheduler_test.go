@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/backend"
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeThermostats struct {
+	backend.Thermostats
+	thermostat *device.Thermostat
+	calls      []string
+	err        error
+}
+
+func (f *fakeThermostats) Get(deviceid string) (*device.Thermostat, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	th := *f.thermostat
+	return &th, nil
+}
+
+func (f *fakeThermostats) SetTargetTemperature(deviceid string, scale device.TemperatureScale, target int) error {
+	f.calls = append(f.calls, fmt.Sprintf("target %d%s", target, scale))
+	return nil
+}
+
+func (f *fakeThermostats) SetTargetTemperatureRange(deviceid string, scale device.TemperatureScale, low, high int) error {
+	f.calls = append(f.calls, fmt.Sprintf("range %d-%d%s", low, high, scale))
+	return nil
+}
+
+func (f *fakeThermostats) SetHVACMode(deviceid string, mode device.HvacMode) error {
+	f.calls = append(f.calls, fmt.Sprintf("mode %s", mode))
+	f.thermostat.HvacMode = mode
+	return nil
+}
+
+type fakeStructures struct {
+	structure *device.Structure
+}
+
+func (f *fakeStructures) Get(structureid string) (*device.Structure, error) {
+	return f.structure, nil
+}
+
+func newTestScheduler(t *testing.T, mode device.HvacMode, away device.AwayState) (*Scheduler, *fakeThermostats, *fakeStructures, *MemoryStore) {
+	th := &fakeThermostats{thermostat: &device.Thermostat{DeviceID: "123", StructureID: "abc", HvacMode: mode}}
+	st := &fakeStructures{structure: &device.Structure{StructureID: "abc", TimeZone: "America/New_York", Away: away}}
+	store := NewMemoryStore()
+	s, err := New(th, st, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Add(Schedule{
+		DeviceID: "123",
+		Scale:    device.Fahrenheit,
+		Days: weekdays(
+			Setpoint{At: Clock(6, 30), Mode: device.HvacModeHeat, Target: 68},
+			Setpoint{At: Clock(8, 0), Mode: device.HvacModeHeatCool, Low: 60, High: 78},
+			Setpoint{At: Clock(22, 0), Target: 62},
+		),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, th, st, store
+}
+
+func Test_ApplyUsesStructureTimeZone(t *testing.T) {
+	s, th, _, store := newTestScheduler(t, device.HvacModeHeat, device.AwayStateHome)
+
+	// 11:00 UTC is 07:00 in New York, so the 06:30 setpoint is active
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+	assert.Equal(t, []string{"target 68F"}, th.calls)
+
+	state, _ := store.Load()
+	loc, _ := time.LoadLocation("America/New_York")
+	assert.True(t, time.Date(2020, 3, 24, 6, 30, 0, 0, loc).Equal(state.Applied["123"]))
+}
+
+func Test_ApplyOncePerSetpoint(t *testing.T) {
+	s, th, _, _ := newTestScheduler(t, device.HvacModeHeat, device.AwayStateHome)
+
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+	assert.NoError(t, s.Apply())
+	assert.Len(t, th.calls, 1, "setpoint should only be applied once")
+
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 12, 30, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+	assert.Equal(t, []string{"target 68F", "mode heat-cool", "range 60-78F"}, th.calls)
+}
+
+func Test_ApplySkipsAwayAndEco(t *testing.T) {
+	s, th, st, _ := newTestScheduler(t, device.HvacModeHeat, device.AwayStateAway)
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+	assert.Empty(t, th.calls)
+
+	th.thermostat.HvacMode = device.HvacModeEco
+	st.structure.Away = device.AwayStateHome
+	assert.NoError(t, s.Apply())
+	assert.Empty(t, th.calls)
+
+	th.thermostat.HvacMode = device.HvacModeHeat
+	assert.NoError(t, s.Apply())
+	assert.Equal(t, []string{"target 68F"}, th.calls, "setpoint should apply once home again")
+}
+
+func Test_ApplyResumesFromStore(t *testing.T) {
+	s, th, st, store := newTestScheduler(t, device.HvacModeHeat, device.AwayStateHome)
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+
+	restarted, err := New(th, st, store)
+	assert.NoError(t, err)
+	restarted.now = s.now
+	for _, sc := range s.schedules {
+		assert.NoError(t, restarted.Add(sc))
+	}
+	assert.NoError(t, restarted.Apply())
+	assert.Len(t, th.calls, 1, "restarted scheduler should not re-apply the setpoint")
+}
+
+func Test_ApplyModeMismatch(t *testing.T) {
+	s, th, _, store := newTestScheduler(t, device.HvacModeHeatCool, device.AwayStateHome)
+
+	// 02:00 UTC is 22:00 in New York; the 22:00 setpoint has a target but no
+	// mode, which cannot be written in heat-cool mode
+	s.now = func() time.Time { return time.Date(2020, 3, 25, 2, 0, 0, 0, time.UTC) }
+	assert.EqualError(t, s.Apply(), "device 123: setpoint at 22:00 cannot be applied in heat-cool mode")
+	assert.Empty(t, th.calls)
+	state, _ := store.Load()
+	assert.Empty(t, state.Applied, "setpoint should not be recorded as applied")
+
+	th.thermostat.HvacMode = device.HvacModeHeat
+	assert.NoError(t, s.Apply())
+	assert.Equal(t, []string{"target 62F"}, th.calls, "setpoint should be retried")
+}
+
+func Test_ApplyBackend(t *testing.T) {
+	f := backend.NewFake(&device.Devices{
+		Thermostats: map[string]*device.Thermostat{"123": {DeviceID: "123", StructureID: "abc", HvacMode: device.HvacModeHeat, CanHeat: true, CanCool: true, TargetTemperatureF: 65}},
+	}, &device.Structure{StructureID: "abc", TimeZone: "America/New_York", Away: device.AwayStateHome})
+	b := f.Backend()
+	s, err := New(b.Thermostats, b.Structures, NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, s.Add(Schedule{
+		DeviceID: "123",
+		Scale:    device.Fahrenheit,
+		Days: weekdays(
+			Setpoint{At: Clock(6, 30), Target: 68},
+			Setpoint{At: Clock(8, 0), Mode: device.HvacModeHeatCool, Low: 60, High: 78},
+		),
+	}))
+
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+	th, _ := f.Thermostat("123")
+	assert.Equal(t, 68, th.TargetTemperatureF)
+
+	s.now = func() time.Time { return time.Date(2020, 3, 24, 12, 30, 0, 0, time.UTC) }
+	assert.NoError(t, s.Apply())
+	th, _ = f.Thermostat("123")
+	assert.Equal(t, device.HvacModeHeatCool, th.HvacMode)
+	assert.Equal(t, 60, th.TargetTemperatureLowF)
+	assert.Equal(t, 78, th.TargetTemperatureHighF)
+}
+
+func Test_ApplyError(t *testing.T) {
+	s, th, _, _ := newTestScheduler(t, device.HvacModeHeat, device.AwayStateHome)
+	th.err = errors.New("Invalid thermostat id: 123")
+	assert.EqualError(t, s.Apply(), "device 123: Invalid thermostat id: 123")
+
+	assert.Error(t, s.Add(Schedule{}))
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest/internal/fileutil"
+)
+
+// State is the persisted scheduler state. Applied maps a device id to the
+// instant the most recently applied setpoint took effect, so a restarted
+// scheduler does not re-apply a setpoint the user has since overridden.
+type State struct {
+	Applied map[string]time.Time `json:"applied"`
+}
+
+// Store loads and saves scheduler state.
+type Store interface {
+	Load() (*State, error)
+	Save(*State) error
+}
+
+// MemoryStore keeps state in memory. It is useful for tests and for
+// processes that do not need to survive restarts.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state State
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{state: State{Applied: map[string]time.Time{}}}
+}
+
+// Load returns a copy of the stored state.
+func (m *MemoryStore) Load() (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copyState(&m.state), nil
+}
+
+// Save replaces the stored state.
+func (m *MemoryStore) Save(s *State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = *copyState(s)
+	return nil
+}
+
+// FileStore persists state as JSON to a file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store backed by the file at path. The file is created
+// on the first Save.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads state from the file. A missing file results in empty state.
+func (f *FileStore) Load() (*State, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return &State{Applied: map[string]time.Time{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	if s.Applied == nil {
+		s.Applied = map[string]time.Time{}
+	}
+	return &s, nil
+}
+
+// Save writes state to the file atomically.
+func (f *FileStore) Save(s *State) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteFile(f.path, b, 0644)
+}
+
+func copyState(s *State) *State {
+	c := &State{Applied: make(map[string]time.Time, len(s.Applied))}
+	for k, v := range s.Applied {
+		c.Applied[k] = v
+	}
+	return c
+}
@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileStore(filepath.Join(dir, "state.json"))
+	s, err := store.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, s.Applied)
+
+	applied := time.Date(2020, 3, 24, 6, 30, 0, 0, time.UTC)
+	s.Applied["123"] = applied
+	assert.NoError(t, store.Save(s))
+
+	s, err = NewFileStore(filepath.Join(dir, "state.json")).Load()
+	assert.NoError(t, err)
+	assert.True(t, applied.Equal(s.Applied["123"]))
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "temporary files should be renamed")
+}
+
+func Test_MemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	s, _ := store.Load()
+	s.Applied["123"] = time.Now()
+
+	loaded, _ := store.Load()
+	assert.Empty(t, loaded.Applied, "state should not be shared until saved")
+
+	assert.NoError(t, store.Save(s))
+	loaded, _ = store.Load()
+	assert.Len(t, loaded.Applied, 1)
+}
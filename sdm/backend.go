@@ -0,0 +1,22 @@
+package sdm
+
+import "github.com/jtsiros/nest/backend"
+
+var (
+	_ backend.Devices       = (*Client)(nil)
+	_ backend.Thermostats   = (*ThermostatService)(nil)
+	_ backend.Cameras       = (*CameraService)(nil)
+	_ backend.SmokeCoAlarms = (*SmokeCoAlarmService)(nil)
+	_ backend.Structures    = (*StructureService)(nil)
+)
+
+// Backend returns the client's services as a backend.Backend.
+func (c *Client) Backend() backend.Backend {
+	return backend.Backend{
+		Devices:       c,
+		Thermostats:   c.Thermostats,
+		Cameras:       c.Cameras,
+		SmokeCoAlarms: c.SmokeCoAlarms,
+		Structures:    c.Structures,
+	}
+}
@@ -0,0 +1,55 @@
+package sdm
+
+import (
+	"context"
+	"io"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+)
+
+// CameraService reads cameras, doorbells and displays with camera traits. Its
+// methods mirror nest.CameraService.
+type CameraService service
+
+// Get fetches a camera given its device id or resource name, converted from
+// its SDM traits.
+func (svc *CameraService) Get(deviceid string) (*device.Camera, error) {
+	d, err := svc.client.Device(deviceid)
+	if err != nil {
+		return nil, err
+	}
+	return d.Camera()
+}
+
+// SetStreaming is not supported by the SDM API.
+func (svc *CameraService) SetStreaming(deviceid string, streaming bool) error {
+	return ErrUnsupported
+}
+
+// SetStreamingForStructure is not supported by the SDM API.
+func (svc *CameraService) SetStreamingForStructure(structureid string, streaming bool) error {
+	return ErrUnsupported
+}
+
+// SetStreamingAll is not supported by the SDM API.
+func (svc *CameraService) SetStreamingAll(streaming bool) error {
+	return ErrUnsupported
+}
+
+// Snapshot is not supported by the SDM API. Event images are available
+// through CameraEventImage.GenerateImage with ExecuteCommand.
+func (svc *CameraService) Snapshot(ctx context.Context, deviceid string) (io.ReadCloser, string, error) {
+	return nil, "", ErrUnsupported
+}
+
+// FetchMedia is not supported by the SDM API. See Snapshot.
+func (svc *CameraService) FetchMedia(ctx context.Context, mediaURL string) (io.ReadCloser, string, error) {
+	return nil, "", ErrUnsupported
+}
+
+// Stream is not supported by the SDM API, which delivers events through
+// Cloud Pub/Sub.
+func (svc *CameraService) Stream(deviceID string) (*nest.Stream, error) {
+	return nil, ErrUnsupported
+}
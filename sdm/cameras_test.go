@@ -0,0 +1,27 @@
+package sdm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CameraGet(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+	c := newTestClient(t, f)
+
+	camera, err := c.Cameras.Get("c1")
+	assert.NoError(t, err)
+	assert.Equal(t, "c1", camera.DeviceID)
+	assert.Equal(t, "Front Door", camera.Name)
+	assert.Equal(t, "Entryway", camera.WhereName)
+	assert.Equal(t, "s1", camera.StructureID)
+	assert.True(t, camera.IsOnline)
+	assert.True(t, camera.IsStreaming)
+
+	_, err = c.Cameras.Get("t1")
+	assert.EqualError(t, err, "device t1 is not a camera")
+
+	assert.Equal(t, ErrUnsupported, c.Cameras.SetStreaming("c1", false))
+}
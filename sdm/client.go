@@ -0,0 +1,206 @@
+// Package sdm is a backend for Google's Smart Device Management (SDM) API,
+// which replaces the Works with Nest API for newer devices. Its services
+// mirror the operations of the nest package's services and are implemented
+// with SDM trait commands, so code written against those operations can use
+// either API. Operations SDM has no equivalent for return ErrUnsupported.
+// See https://developers.google.com/nest/device-access/api
+package sdm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+)
+
+// APIURL provides the default SDM REST API endpoint.
+const APIURL = "https://smartdevicemanagement.googleapis.com/v1"
+
+// ErrUnsupported is returned for operations the SDM API does not provide.
+var ErrUnsupported = errors.New("sdm: operation not supported by the SDM API")
+
+// Config represents configuration for the SDM API. ProjectID is the Device
+// Access project id; APIURL defaults to APIURL.
+type Config struct {
+	ProjectID string
+	APIURL    string
+}
+
+// Client provides access to the devices of a Device Access project. The HTTP
+// client is expected to handle OAuth2 for the Google account.
+type Client struct {
+	httpClient    *http.Client
+	baseURL       *url.URL
+	project       string
+	Thermostats   *ThermostatService
+	Cameras       *CameraService
+	SmokeCoAlarms *SmokeCoAlarmService
+	Structures    *StructureService
+}
+
+type service struct {
+	client *Client
+}
+
+// NewClient creates a new SDM API client.
+func NewClient(cfg Config, client *http.Client) (*Client, error) {
+	if cfg.ProjectID == "" {
+		return nil, errors.New("sdm: project id is required")
+	}
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = APIURL
+	}
+	u, err := url.Parse(strings.TrimSuffix(apiURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("not a properly formed API URL: %v", err)
+	}
+	c := &Client{
+		httpClient: client,
+		baseURL:    u,
+		project:    cfg.ProjectID,
+	}
+	c.Thermostats = &ThermostatService{client: c}
+	c.Cameras = &CameraService{client: c}
+	c.SmokeCoAlarms = &SmokeCoAlarmService{client: c}
+	c.Structures = &StructureService{client: c}
+	return c, nil
+}
+
+// Devices lists the thermostats and cameras of the project, keyed by device id.
+// Doorbells and displays with camera traits are listed as cameras.
+// See https://developers.google.com/nest/device-access/reference/rest/v1/enterprises.devices/list
+//
+func (c *Client) Devices() (*device.Devices, error) {
+	var resp struct {
+		Devices []*Device `json:"devices"`
+	}
+	if err := c.do(http.MethodGet, c.projectPath("devices"), nil, &resp); err != nil {
+		return nil, err
+	}
+	devices := &device.Devices{
+		Thermostats: map[string]*device.Thermostat{},
+		Cameras:     map[string]*device.Camera{},
+	}
+	for _, d := range resp.Devices {
+		switch {
+		case d.Type == TypeThermostat:
+			th, err := d.Thermostat()
+			if err != nil {
+				return nil, err
+			}
+			devices.Thermostats[d.ID()] = th
+		case d.IsCamera():
+			c, err := d.Camera()
+			if err != nil {
+				return nil, err
+			}
+			devices.Cameras[d.ID()] = c
+		}
+	}
+	return devices, nil
+}
+
+// Device fetches the raw SDM device, given its id or full resource name.
+// See https://developers.google.com/nest/device-access/reference/rest/v1/enterprises.devices/get
+//
+func (c *Client) Device(deviceid string) (*Device, error) {
+	var d Device
+	if err := c.do(http.MethodGet, c.devicePath(deviceid), nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ExecuteCommand executes a trait command on a device, e.g.
+// "sdm.devices.commands.ThermostatMode.SetMode" with {"mode": "HEAT"}. The
+// command results are decoded into v when it is not nil.
+// See https://developers.google.com/nest/device-access/reference/rest/v1/enterprises.devices/executeCommand
+//
+func (c *Client) ExecuteCommand(deviceid, command string, params map[string]interface{}, v interface{}) error {
+	body := struct {
+		Command string                 `json:"command"`
+		Params  map[string]interface{} `json:"params"`
+	}{command, params}
+	if body.Params == nil {
+		body.Params = map[string]interface{}{}
+	}
+	var resp struct {
+		Results json.RawMessage `json:"results"`
+	}
+	if err := c.do(http.MethodPost, c.devicePath(deviceid)+":executeCommand", body, &resp); err != nil {
+		return err
+	}
+	if v == nil || len(resp.Results) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Results, v)
+}
+
+func (c *Client) projectPath(collection string) string {
+	return fmt.Sprintf("enterprises/%s/%s", c.project, collection)
+}
+
+// devicePath returns the resource name of a device given its id or full name.
+func (c *Client) devicePath(deviceid string) string {
+	if strings.HasPrefix(deviceid, "enterprises/") {
+		return deviceid
+	}
+	return c.projectPath("devices/" + deviceid)
+}
+
+// do sends a request to the resource at path and decodes the JSON response
+// into v. API errors are returned as nest.Error so callers can handle both
+// APIs alike.
+func (c *Client) do(method, path string, body interface{}, v interface{}) error {
+	var buf io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		buf = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.baseURL.String()+"/"+path, buf)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+			return fmt.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		return nest.Error{
+			Err:        apiErr.Error.Status,
+			Message:    apiErr.Error.Message,
+			StatusCode: resp.StatusCode,
+		}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
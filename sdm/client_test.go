@@ -0,0 +1,150 @@
+package sdm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jtsiros/nest"
+	"github.com/stretchr/testify/assert"
+)
+
+const thermostatJSON = `{
+  "name": "enterprises/project-1/devices/t1",
+  "type": "sdm.devices.types.THERMOSTAT",
+  "traits": {
+    "sdm.devices.traits.Info": {"customName": ""},
+    "sdm.devices.traits.Connectivity": {"status": "ONLINE"},
+    "sdm.devices.traits.Humidity": {"ambientHumidityPercent": 41.6},
+    "sdm.devices.traits.Temperature": {"ambientTemperatureCelsius": 20.5},
+    "sdm.devices.traits.Settings": {"temperatureScale": "FAHRENHEIT"},
+    "sdm.devices.traits.Fan": {"timerMode": "OFF"},
+    "sdm.devices.traits.ThermostatMode": {"mode": "HEAT", "availableModes": ["HEAT", "COOL", "HEATCOOL", "OFF"]},
+    "sdm.devices.traits.ThermostatEco": {"availableModes": ["OFF", "MANUAL_ECO"], "mode": "%s", "heatCelsius": 15.5, "coolCelsius": 26.6},
+    "sdm.devices.traits.ThermostatHvac": {"status": "HEATING"},
+    "sdm.devices.traits.ThermostatTemperatureSetpoint": {"heatCelsius": 21}
+  },
+  "parentRelations": [{"parent": "enterprises/project-1/structures/s1/rooms/r1", "displayName": "Living Room"}]
+}`
+
+const cameraJSON = `{
+  "name": "enterprises/project-1/devices/c1",
+  "type": "sdm.devices.types.DOORBELL",
+  "traits": {
+    "sdm.devices.traits.Info": {"customName": "Front Door"},
+    "sdm.devices.traits.CameraLiveStream": {"supportedProtocols": ["RTSP"]},
+    "sdm.devices.traits.CameraPerson": {}
+  },
+  "parentRelations": [{"parent": "enterprises/project-1/structures/s1/rooms/r2", "displayName": "Entryway"}]
+}`
+
+const structureJSON = `{
+  "name": "enterprises/project-1/structures/s1",
+  "traits": {"sdm.structures.traits.Info": {"customName": "Home"}}
+}`
+
+const roomsJSON = `{"rooms": [
+  {"name": "enterprises/project-1/structures/s1/rooms/r1", "traits": {"sdm.structures.traits.RoomInfo": {"customName": "Living Room"}}},
+  {"name": "enterprises/project-1/structures/s1/rooms/r2", "traits": {"sdm.structures.traits.RoomInfo": {"customName": "Entryway"}}}
+]}`
+
+type command struct {
+	Device  string                 `json:"-"`
+	Command string                 `json:"command"`
+	Params  map[string]interface{} `json:"params"`
+}
+
+// fakeSDM serves the devices of project-1 and records executed commands.
+type fakeSDM struct {
+	*httptest.Server
+	mu       sync.Mutex
+	eco      string
+	commands []command
+}
+
+func newFakeSDM() *fakeSDM {
+	f := &fakeSDM{eco: "OFF"}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *fakeSDM) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	thermostat := fmt.Sprintf(thermostatJSON, f.eco)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/enterprises/project-1/devices":
+		fmt.Fprintf(w, `{"devices": [%s, %s]}`, thermostat, cameraJSON)
+	case r.Method == http.MethodGet && r.URL.Path == "/enterprises/project-1/devices/t1":
+		fmt.Fprint(w, thermostat)
+	case r.Method == http.MethodGet && r.URL.Path == "/enterprises/project-1/devices/c1":
+		fmt.Fprint(w, cameraJSON)
+	case r.Method == http.MethodGet && r.URL.Path == "/enterprises/project-1/structures/s1":
+		fmt.Fprint(w, structureJSON)
+	case r.Method == http.MethodGet && r.URL.Path == "/enterprises/project-1/structures/s1/rooms":
+		fmt.Fprint(w, roomsJSON)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":executeCommand"):
+		var c command
+		json.NewDecoder(r.Body).Decode(&c)
+		c.Device = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/enterprises/project-1/devices/"), ":executeCommand")
+		if c.Command == "sdm.devices.commands.ThermostatTemperatureSetpoint.SetRange" && c.Params["heatCelsius"].(float64) > 30 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"code": 400, "message": "Heat value out of range.", "status": "INVALID_ARGUMENT"}}`)
+			return
+		}
+		f.commands = append(f.commands, c)
+		fmt.Fprint(w, `{"results": {}}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"code": 404, "message": "Device not found.", "status": "NOT_FOUND"}}`)
+	}
+}
+
+func newTestClient(t *testing.T, f *fakeSDM) *Client {
+	c, err := NewClient(Config{ProjectID: "project-1", APIURL: f.URL}, f.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func Test_NewClient(t *testing.T) {
+	_, err := NewClient(Config{}, http.DefaultClient)
+	assert.EqualError(t, err, "sdm: project id is required")
+
+	c, err := NewClient(Config{ProjectID: "project-1"}, http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, APIURL, c.baseURL.String())
+}
+
+func Test_Devices(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+
+	devices, err := newTestClient(t, f).Devices()
+	assert.NoError(t, err)
+	assert.Len(t, devices.Thermostats, 1)
+	assert.Len(t, devices.Cameras, 1)
+	assert.Equal(t, "Living Room", devices.Thermostats["t1"].Name)
+	assert.Equal(t, "Front Door (Entryway)", devices.Cameras["c1"].NameLong)
+}
+
+func Test_ExecuteCommandError(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+
+	err := newTestClient(t, f).ExecuteCommand("t1", "sdm.devices.commands.ThermostatTemperatureSetpoint.SetRange", map[string]interface{}{"heatCelsius": 40, "coolCelsius": 45}, nil)
+	if assert.IsType(t, nest.Error{}, err) {
+		apiErr := err.(nest.Error)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.Equal(t, "INVALID_ARGUMENT", apiErr.Err)
+		assert.Equal(t, "Heat value out of range.", apiErr.Error())
+	}
+
+	_, err = newTestClient(t, f).Device("unknown")
+	assert.EqualError(t, err, "Device not found.")
+}
@@ -0,0 +1,231 @@
+package sdm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// SDM device types
+const (
+	TypeThermostat = "sdm.devices.types.THERMOSTAT"
+	TypeCamera     = "sdm.devices.types.CAMERA"
+	TypeDoorbell   = "sdm.devices.types.DOORBELL"
+	TypeDisplay    = "sdm.devices.types.DISPLAY"
+)
+
+// Device is a device as returned by the SDM API. Traits holds the raw JSON of
+// every trait the device supports, keyed by trait name.
+type Device struct {
+	Name            string                     `json:"name"`
+	Type            string                     `json:"type"`
+	Traits          map[string]json.RawMessage `json:"traits"`
+	ParentRelations []ParentRelation           `json:"parentRelations,omitempty"`
+}
+
+// ParentRelation links a device to the structure or room it is assigned to.
+type ParentRelation struct {
+	Parent      string `json:"parent"`
+	DisplayName string `json:"displayName"`
+}
+
+// ID returns the device id, the last segment of the device's resource name.
+func (d *Device) ID() string {
+	return lastSegment(d.Name)
+}
+
+// HasTrait reports whether the device supports the named trait, e.g.
+// TraitThermostatEco.
+func (d *Device) HasTrait(name string) bool {
+	_, ok := d.Traits[name]
+	return ok
+}
+
+// Trait decodes the named trait into v, e.g. a *ThermostatEco for
+// TraitThermostatEco.
+func (d *Device) Trait(name string, v interface{}) error {
+	raw, ok := d.Traits[name]
+	if !ok {
+		return fmt.Errorf("device %s has no %s trait", d.ID(), name)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// DecodeTraits decodes every known trait of the device.
+func (d *Device) DecodeTraits() (*Traits, error) {
+	b, err := json.Marshal(d.Traits)
+	if err != nil {
+		return nil, err
+	}
+	var t Traits
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("device %s: %v", d.ID(), err)
+	}
+	return &t, nil
+}
+
+// IsCamera reports whether the device is a camera, doorbell or display.
+func (d *Device) IsCamera() bool {
+	switch d.Type {
+	case TypeCamera, TypeDoorbell, TypeDisplay:
+		return true
+	}
+	return false
+}
+
+// Thermostat converts a thermostat to the Works with Nest thermostat model.
+// Setpoints and the eco range are converted to Fahrenheit as well. Devices
+// without the Connectivity trait are reported online.
+func (d *Device) Thermostat() (*device.Thermostat, error) {
+	if d.Type != TypeThermostat {
+		return nil, fmt.Errorf("device %s is not a thermostat", d.ID())
+	}
+	t, err := d.DecodeTraits()
+	if err != nil {
+		return nil, err
+	}
+
+	th := &device.Thermostat{DeviceID: d.ID(), IsOnline: true}
+	d.names(t, &th.Name, &th.NameLong, &th.WhereName, &th.StructureID)
+	if t.Connectivity != nil {
+		th.IsOnline = t.Connectivity.Online()
+	}
+	if t.Humidity != nil {
+		th.Humidity = int(math.Round(t.Humidity.AmbientHumidityPercent))
+	}
+	if t.Temperature != nil {
+		th.AmbientTemperatureC = t.Temperature.AmbientTemperatureCelsius
+		th.AmbientTemperatureF = fahrenheit(t.Temperature.AmbientTemperatureCelsius)
+	}
+	if t.Settings != nil {
+		th.TemperatureScale = device.Celsius
+		if t.Settings.TemperatureScale == "FAHRENHEIT" {
+			th.TemperatureScale = device.Fahrenheit
+		}
+	}
+	if t.Fan != nil {
+		th.HasFan = true
+		th.FanTimerActive = t.Fan.TimerMode == "ON"
+		th.FanTimerTimeout = t.Fan.TimerTimeout
+	}
+	if t.ThermostatHvac != nil {
+		switch t.ThermostatHvac.Status {
+		case "HEATING":
+			th.HvacState = device.HvacStateHeating
+		case "COOLING":
+			th.HvacState = device.HvacStateCooling
+		default:
+			th.HvacState = device.HvacStateOff
+		}
+	}
+	if mode := t.ThermostatMode; mode != nil {
+		th.HvacMode = hvacMode(mode.Mode)
+		th.CanHeat = mode.Supports("HEAT")
+		th.CanCool = mode.Supports("COOL")
+	}
+	if eco := t.ThermostatEco; eco != nil {
+		th.EcoTemperatureLowC = eco.HeatCelsius
+		th.EcoTemperatureLowF = fahrenheit(eco.HeatCelsius)
+		th.EcoTemperatureHighC = eco.CoolCelsius
+		th.EcoTemperatureHighF = fahrenheit(eco.CoolCelsius)
+		if eco.On() {
+			th.PreviousHvacMode = th.HvacMode
+			th.HvacMode = device.HvacModeEco
+		}
+	}
+	if setpoint := t.ThermostatTemperatureSetpoint; setpoint != nil {
+		switch th.HvacMode {
+		case device.HvacModeHeat:
+			th.TargetTemperatureC = setpoint.HeatCelsius
+		case device.HvacModeCool:
+			th.TargetTemperatureC = setpoint.CoolCelsius
+		case device.HvacModeHeatCool:
+			th.TargetTemperatureLowC = setpoint.HeatCelsius
+			th.TargetTemperatureLowF = fahrenheit(setpoint.HeatCelsius)
+			th.TargetTemperatureHighC = setpoint.CoolCelsius
+			th.TargetTemperatureHighF = fahrenheit(setpoint.CoolCelsius)
+		}
+		if th.TargetTemperatureC != 0 {
+			th.TargetTemperatureF = fahrenheit(th.TargetTemperatureC)
+		}
+	}
+	return th, nil
+}
+
+// Camera converts a camera, doorbell or display to the Works with Nest camera
+// model. IsStreaming reports whether the device can generate live streams.
+func (d *Device) Camera() (*device.Camera, error) {
+	if !d.IsCamera() {
+		return nil, fmt.Errorf("device %s is not a camera", d.ID())
+	}
+	t, err := d.DecodeTraits()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &device.Camera{DeviceID: d.ID(), IsOnline: true}
+	d.names(t, &c.Name, &c.NameLong, &c.WhereName, &c.StructureID)
+	if t.Connectivity != nil {
+		c.IsOnline = t.Connectivity.Online()
+	}
+	c.IsStreaming = t.CameraLiveStream != nil
+	return c, nil
+}
+
+// names sets the device name, long name, room name and structure id from the
+// Info trait and the device's parent relations.
+func (d *Device) names(t *Traits, name, nameLong, whereName, structureID *string) {
+	for _, p := range d.ParentRelations {
+		*whereName = p.DisplayName
+		*structureID = structureFromParent(p.Parent)
+	}
+	if t.Info != nil {
+		*name = t.Info.CustomName
+	}
+	if *name == "" {
+		*name = *whereName
+	}
+	*nameLong = *name
+	if *whereName != "" && *whereName != *name {
+		*nameLong = *name + " (" + *whereName + ")"
+	}
+}
+
+// structureFromParent returns the structure id of a parent resource name such
+// as "enterprises/p/structures/s/rooms/r".
+func structureFromParent(parent string) string {
+	parts := strings.Split(parent, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "structures" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+func lastSegment(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+func hvacMode(mode string) device.HvacMode {
+	switch mode {
+	case "HEAT":
+		return device.HvacModeHeat
+	case "COOL":
+		return device.HvacModeCool
+	case "HEATCOOL":
+		return device.HvacModeHeatCool
+	}
+	return device.HvacModeOff
+}
+
+func fahrenheit(c float64) int {
+	return int(math.Round(c*9/5 + 32))
+}
+
+func celsius(f int) float64 {
+	return float64(f-32) * 5 / 9
+}
@@ -0,0 +1,85 @@
+package sdm
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeDevice(t *testing.T, s string) *Device {
+	var d Device
+	if err := json.Unmarshal([]byte(s), &d); err != nil {
+		t.Fatal(err)
+	}
+	return &d
+}
+
+func Test_DeviceTraits(t *testing.T) {
+	d := decodeDevice(t, fmt.Sprintf(thermostatJSON, "OFF"))
+	assert.Equal(t, "t1", d.ID())
+	assert.True(t, d.HasTrait(TraitThermostatEco))
+	assert.False(t, d.HasTrait(TraitCameraLiveStream))
+
+	var eco ThermostatEco
+	assert.NoError(t, d.Trait(TraitThermostatEco, &eco))
+	assert.False(t, eco.On())
+	assert.Equal(t, 26.6, eco.CoolCelsius)
+	assert.EqualError(t, d.Trait(TraitCameraImage, &CameraImage{}), "device t1 has no sdm.devices.traits.CameraImage trait")
+
+	traits, err := d.DecodeTraits()
+	assert.NoError(t, err)
+	assert.Equal(t, &Humidity{AmbientHumidityPercent: 41.6}, traits.Humidity)
+	assert.True(t, traits.ThermostatMode.Supports("HEATCOOL"))
+	assert.True(t, traits.Connectivity.Online())
+	assert.Nil(t, traits.CameraLiveStream)
+
+	camera := decodeDevice(t, cameraJSON)
+	traits, err = camera.DecodeTraits()
+	assert.NoError(t, err)
+	assert.NotNil(t, traits.CameraPerson)
+	assert.Equal(t, []string{"RTSP"}, traits.CameraLiveStream.SupportedProtocols)
+	assert.Nil(t, traits.CameraMotion)
+}
+
+func Test_DeviceThermostat(t *testing.T) {
+	tests := []struct {
+		name   string
+		traits string
+		want   device.Thermostat
+	}{
+		{
+			"heat-cool",
+			`{"sdm.devices.traits.ThermostatMode": {"mode": "HEATCOOL", "availableModes": ["HEAT", "COOL", "HEATCOOL", "OFF"]},
+			  "sdm.devices.traits.ThermostatTemperatureSetpoint": {"heatCelsius": 20, "coolCelsius": 25}}`,
+			device.Thermostat{DeviceID: "t1", IsOnline: true, CanHeat: true, CanCool: true, HvacMode: device.HvacModeHeatCool,
+				TargetTemperatureLowC: 20, TargetTemperatureLowF: 68, TargetTemperatureHighC: 25, TargetTemperatureHighF: 77},
+		},
+		{
+			"offline heat only",
+			`{"sdm.devices.traits.Connectivity": {"status": "OFFLINE"},
+			  "sdm.devices.traits.ThermostatMode": {"mode": "OFF", "availableModes": ["HEAT", "OFF"]},
+			  "sdm.devices.traits.Info": {"customName": "Hallway"}}`,
+			device.Thermostat{DeviceID: "t1", Name: "Hallway", NameLong: "Hallway", CanHeat: true, HvacMode: device.HvacModeOff},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := decodeDevice(t, `{"name": "enterprises/project-1/devices/t1", "type": "sdm.devices.types.THERMOSTAT", "traits": `+tt.traits+`}`)
+			th, err := d.Thermostat()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *th)
+		})
+	}
+}
+
+func Test_DeviceConversionErrors(t *testing.T) {
+	d := decodeDevice(t, `{"name": "enterprises/project-1/devices/t1", "type": "sdm.devices.types.THERMOSTAT",
+		"traits": {"sdm.devices.traits.Humidity": {"ambientHumidityPercent": "high"}}}`)
+	_, err := d.Thermostat()
+	assert.Error(t, err)
+	_, err = d.Camera()
+	assert.EqualError(t, err, "device t1 is not a camera")
+}
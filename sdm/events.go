@@ -0,0 +1,313 @@
+package sdm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+)
+
+// SDM device event names
+// See https://developers.google.com/nest/device-access/api/events
+const (
+	EventCameraMotion  = "sdm.devices.events.CameraMotion.Motion"
+	EventCameraPerson  = "sdm.devices.events.CameraPerson.Person"
+	EventCameraSound   = "sdm.devices.events.CameraSound.Sound"
+	EventDoorbellChime = "sdm.devices.events.DoorbellChime.Chime"
+)
+
+// Event is an event published by the SDM API to a Pub/Sub topic.
+type Event struct {
+	EventID          string          `json:"eventId"`
+	Timestamp        time.Time       `json:"timestamp"`
+	ResourceUpdate   *ResourceUpdate `json:"resourceUpdate,omitempty"`
+	UserID           string          `json:"userId,omitempty"`
+	EventThreadID    string          `json:"eventThreadId,omitempty"`
+	EventThreadState string          `json:"eventThreadState,omitempty"`
+	ResourceGroup    []string        `json:"resourceGroup,omitempty"`
+	RelationUpdate   json.RawMessage `json:"relationUpdate,omitempty"`
+}
+
+// ResourceUpdate holds the traits that changed on a device and the device
+// events that occurred.
+type ResourceUpdate struct {
+	Name   string                     `json:"name"`
+	Traits map[string]json.RawMessage `json:"traits,omitempty"`
+	Events map[string]DeviceEvent     `json:"events,omitempty"`
+}
+
+// DeviceEvent identifies a camera or doorbell event. Events of the same
+// session describe the same activity.
+type DeviceEvent struct {
+	EventSessionID string `json:"eventSessionId"`
+	EventID        string `json:"eventId"`
+}
+
+// Ingester turns SDM events into the nest package's event type, so the
+// stream handlers of the library can be used with SDM devices. Trait updates
+// are merged into a cached copy of each device, and camera and doorbell events
+// are reported as the camera's last_event. Every update is emitted as a "put"
+// event carrying the full converted device.
+type Ingester struct {
+	client *Client
+
+	mu       sync.Mutex
+	devices  map[string]*Device
+	sessions map[string]*cameraSession
+
+	sendMu    sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	done      chan struct{}
+	events    chan nest.Event
+}
+
+type cameraSession struct {
+	id    string
+	event device.CameraEvent
+}
+
+// NewIngester creates an ingester. When client is not nil, devices not yet
+// cached are fetched with it; otherwise their type is inferred from the
+// updated traits and events.
+func NewIngester(client *Client) *Ingester {
+	return &Ingester{
+		client:   client,
+		devices:  map[string]*Device{},
+		sessions: map[string]*cameraSession{},
+		done:     make(chan struct{}),
+		events:   make(chan nest.Event),
+	}
+}
+
+// Events returns the channel receiving ingested events. It is closed by Close.
+func (in *Ingester) Events() <-chan nest.Event {
+	return in.events
+}
+
+// Close stops delivering events and closes the events channel. It may be
+// called more than once.
+func (in *Ingester) Close() error {
+	in.closeOnce.Do(func() {
+		close(in.done)
+		in.sendMu.Lock()
+		defer in.sendMu.Unlock()
+		in.closed = true
+		close(in.events)
+	})
+	return nil
+}
+
+// Seed adds devices to the cache, e.g. from a device listing at startup.
+func (in *Ingester) Seed(devices ...*Device) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, d := range devices {
+		in.devices[d.Name] = d
+	}
+}
+
+// Ingest decodes an SDM event, the data of a Pub/Sub message, and returns the
+// resulting events. Events for devices other than thermostats and cameras,
+// and events without a resource update, produce no events.
+func (in *Ingester) Ingest(data []byte) ([]nest.Event, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("sdm: decoding event: %v", err)
+	}
+	u := e.ResourceUpdate
+	if u == nil || !strings.Contains(u.Name, "/devices/") {
+		return nil, nil
+	}
+
+	// devices are fetched without holding the lock
+	in.mu.Lock()
+	_, cached := in.devices[u.Name]
+	in.mu.Unlock()
+	var fetched *Device
+	if !cached && in.client != nil {
+		var err error
+		if fetched, err = in.client.Device(u.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	d := in.device(u, fetched)
+	for name, raw := range u.Traits {
+		merged, err := mergeTrait(d.Traits[name], raw)
+		if err != nil {
+			return nil, fmt.Errorf("sdm: merging trait %s: %v", name, err)
+		}
+		d.Traits[name] = merged
+	}
+
+	switch {
+	case d.Type == TypeThermostat:
+		th, err := d.Thermostat()
+		if err != nil {
+			return nil, err
+		}
+		event, err := newEvent(nest.Thermostats, th.DeviceID, th)
+		if err != nil {
+			return nil, err
+		}
+		return []nest.Event{event}, nil
+	case d.IsCamera():
+		return in.cameraEvents(d, &e)
+	}
+	return nil, nil
+}
+
+// cameraEvents returns the events for a camera update. A new event session
+// ends the previous one if it was not ended explicitly.
+func (in *Ingester) cameraEvents(d *Device, e *Event) ([]nest.Event, error) {
+	c, err := d.Camera()
+	if err != nil {
+		return nil, err
+	}
+	emit := func(session *cameraSession) (nest.Event, error) {
+		c := *c
+		if session != nil {
+			event := session.event
+			c.LastEvent = &event
+		}
+		return newEvent(nest.Cameras, c.DeviceID, &c)
+	}
+
+	session := in.sessions[d.Name]
+	var events []nest.Event
+	for name, de := range e.ResourceUpdate.Events {
+		id := de.EventSessionID
+		if e.EventThreadID != "" {
+			id = e.EventThreadID
+		}
+		if session == nil || session.id != id {
+			if session != nil && session.event.InProgress() {
+				session.event.EndTime = e.Timestamp
+				event, err := emit(session)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, event)
+			}
+			session = &cameraSession{id: id, event: device.CameraEvent{StartTime: e.Timestamp}}
+			in.sessions[d.Name] = session
+		}
+		switch name {
+		case EventCameraMotion:
+			session.event.HasMotion = true
+		case EventCameraPerson:
+			session.event.HasPerson = true
+		case EventCameraSound:
+			session.event.HasSound = true
+		}
+	}
+	if session != nil && e.EventThreadState == "ENDED" {
+		session.event.EndTime = e.Timestamp
+	}
+
+	event, err := emit(session)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, event), nil
+}
+
+// device returns the cached device for an update, caching fetched or a new
+// device when it is not cached yet. The type of a device created from updates
+// is inferred from the first update that identifies it, e.g. a thermostat
+// first seen with only a Temperature trait.
+func (in *Ingester) device(u *ResourceUpdate, fetched *Device) *Device {
+	if d, ok := in.devices[u.Name]; ok {
+		if d.Type == "" && in.client == nil {
+			d.Type = inferType(u)
+		}
+		return d
+	}
+	d := fetched
+	if d == nil {
+		d = &Device{Name: u.Name, Type: inferType(u)}
+	}
+	if d.Traits == nil {
+		d.Traits = map[string]json.RawMessage{}
+	}
+	in.devices[u.Name] = d
+	return d
+}
+
+// inferType guesses the type of a device from the traits and events of an update.
+func inferType(u *ResourceUpdate) string {
+	for name := range u.Traits {
+		if strings.HasPrefix(name, "sdm.devices.traits.Thermostat") {
+			return TypeThermostat
+		}
+		if strings.HasPrefix(name, "sdm.devices.traits.Camera") {
+			return TypeCamera
+		}
+	}
+	for name := range u.Events {
+		if strings.HasPrefix(name, "sdm.devices.events.DoorbellChime") {
+			return TypeDoorbell
+		}
+		if strings.HasPrefix(name, "sdm.devices.events.Camera") {
+			return TypeCamera
+		}
+	}
+	return ""
+}
+
+// mergeTrait applies a partial trait update to the cached trait.
+func mergeTrait(cached, update json.RawMessage) (json.RawMessage, error) {
+	if len(cached) == 0 {
+		return update, nil
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(cached, &fields); err != nil {
+		return nil, err
+	}
+	var changed map[string]json.RawMessage
+	if err := json.Unmarshal(update, &changed); err != nil {
+		return nil, err
+	}
+	for k, v := range changed {
+		fields[k] = v
+	}
+	return json.Marshal(fields)
+}
+
+func newEvent(et nest.EventsType, id string, data interface{}) (nest.Event, error) {
+	b, err := json.Marshal(struct {
+		Path string      `json:"path"`
+		Data interface{} `json:"data"`
+	}{fmt.Sprintf("/devices/%s/%s", et, id), data})
+	if err != nil {
+		return nest.Event{}, err
+	}
+	return nest.NewEvent("put", b), nil
+}
+
+// send delivers events to the events channel. It returns false when the
+// ingester is closed or done is closed before all events are delivered.
+func (in *Ingester) send(done <-chan struct{}, events []nest.Event) bool {
+	in.sendMu.RLock()
+	defer in.sendMu.RUnlock()
+	if in.closed {
+		return false
+	}
+	for _, e := range events {
+		select {
+		case in.events <- e:
+		case <-in.done:
+			return false
+		case <-done:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package sdm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func thermostatFrom(t *testing.T, events []nest.Event) *device.Thermostat {
+	if !assert.Len(t, events, 1) {
+		t.FailNow()
+	}
+	et, id, data, err := events[0].GetEvent()
+	assert.NoError(t, err)
+	assert.Equal(t, nest.Thermostats, et)
+	assert.Equal(t, "t1", id)
+	return data.(*device.Thermostat)
+}
+
+func cameraEventsFrom(t *testing.T, events []nest.Event) []*device.CameraEvent {
+	var out []*device.CameraEvent
+	for _, e := range events {
+		et, _, data, err := e.GetEvent()
+		assert.NoError(t, err)
+		assert.Equal(t, nest.Cameras, et)
+		out = append(out, data.(*device.Camera).LastEvent)
+	}
+	return out
+}
+
+func Test_IngestTraitUpdate(t *testing.T) {
+	in := NewIngester(nil)
+	in.Seed(decodeDevice(t, fmt.Sprintf(thermostatJSON, "OFF")))
+
+	events, err := in.Ingest([]byte(`{
+		"eventId": "e1",
+		"timestamp": "2020-03-24T11:00:00Z",
+		"resourceUpdate": {
+			"name": "enterprises/project-1/devices/t1",
+			"traits": {
+				"sdm.devices.traits.ThermostatMode": {"mode": "COOL"},
+				"sdm.devices.traits.ThermostatTemperatureSetpoint": {"coolCelsius": 24}
+			}
+		}
+	}`))
+	assert.NoError(t, err)
+	th := thermostatFrom(t, events)
+	assert.Equal(t, device.HvacModeCool, th.HvacMode)
+	assert.True(t, th.CanHeat, "available modes should be kept from the cached trait")
+	assert.Equal(t, 24.0, th.TargetTemperatureC)
+	assert.Equal(t, 42, th.Humidity)
+
+	// later partial updates build on the merged state
+	events, err = in.Ingest([]byte(`{"resourceUpdate": {"name": "enterprises/project-1/devices/t1",
+		"traits": {"sdm.devices.traits.Temperature": {"ambientTemperatureCelsius": 25}}}}`))
+	assert.NoError(t, err)
+	th = thermostatFrom(t, events)
+	assert.Equal(t, device.HvacModeCool, th.HvacMode)
+	assert.Equal(t, 77, th.AmbientTemperatureF)
+}
+
+func Test_IngestInfersTypeLater(t *testing.T) {
+	in := NewIngester(nil)
+
+	// the first update does not identify the device as a thermostat
+	events, err := in.Ingest([]byte(`{"resourceUpdate": {"name": "enterprises/project-1/devices/t1",
+		"traits": {"sdm.devices.traits.Temperature": {"ambientTemperatureCelsius": 21}}}}`))
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	events, err = in.Ingest([]byte(`{"resourceUpdate": {"name": "enterprises/project-1/devices/t1",
+		"traits": {"sdm.devices.traits.ThermostatHvac": {"status": "HEATING"}}}}`))
+	assert.NoError(t, err)
+	th := thermostatFrom(t, events)
+	assert.Equal(t, device.HvacStateHeating, th.HvacState)
+	assert.Equal(t, 21.0, th.AmbientTemperatureC, "traits of earlier updates should be kept")
+}
+
+func Test_IngesterCloseTwice(t *testing.T) {
+	in := NewIngester(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, in.Close())
+		}()
+	}
+	wg.Wait()
+	_, ok := <-in.Events()
+	assert.False(t, ok)
+}
+
+func Test_IngestFetchesUnknownDevices(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+
+	in := NewIngester(newTestClient(t, f))
+	events, err := in.Ingest([]byte(`{"resourceUpdate": {"name": "enterprises/project-1/devices/t1",
+		"traits": {"sdm.devices.traits.Humidity": {"ambientHumidityPercent": 50}}}}`))
+	assert.NoError(t, err)
+	th := thermostatFrom(t, events)
+	assert.Equal(t, 50, th.Humidity)
+	assert.Equal(t, "Living Room", th.Name)
+}
+
+func Test_IngestFetchWithoutLock(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+
+	in := NewIngester(newTestClient(t, f))
+	in.Seed(&Device{Name: "enterprises/project-1/devices/t2", Type: TypeThermostat, Traits: map[string]json.RawMessage{}})
+
+	// block fetching t1 until the cached t2 has been ingested
+	f.mu.Lock()
+	fetched := make(chan error, 1)
+	go func() {
+		_, err := in.Ingest([]byte(`{"resourceUpdate": {"name": "enterprises/project-1/devices/t1",
+			"traits": {"sdm.devices.traits.Humidity": {"ambientHumidityPercent": 50}}}}`))
+		fetched <- err
+	}()
+
+	ingested := make(chan error, 1)
+	go func() {
+		_, err := in.Ingest([]byte(`{"resourceUpdate": {"name": "enterprises/project-1/devices/t2",
+			"traits": {"sdm.devices.traits.Humidity": {"ambientHumidityPercent": 40}}}}`))
+		ingested <- err
+	}()
+	select {
+	case err := <-ingested:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("cached device was blocked by a device fetch")
+	}
+	f.mu.Unlock()
+	assert.NoError(t, <-fetched)
+}
+
+func Test_IngestIgnored(t *testing.T) {
+	in := NewIngester(nil)
+	for _, data := range []string{
+		`{"relationUpdate": {"type": "CREATED", "subject": "enterprises/p/devices/d", "object": "enterprises/p/structures/s"}}`,
+		`{"resourceUpdate": {"name": "enterprises/p/structures/s", "traits": {"sdm.structures.traits.Info": {"customName": "Home"}}}}`,
+	} {
+		events, err := in.Ingest([]byte(data))
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	}
+	_, err := in.Ingest([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func Test_IngestCameraEvents(t *testing.T) {
+	start := time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)
+	camera := func(at time.Time, thread, state, event string) []byte {
+		return []byte(fmt.Sprintf(`{"timestamp": %q, "eventThreadId": %q, "eventThreadState": %q,
+			"resourceUpdate": {"name": "enterprises/project-1/devices/c1", "events": {%q: {"eventSessionId": "s", "eventId": "e"}}}}`,
+			at.Format(time.RFC3339), thread, state, event))
+	}
+
+	in := NewIngester(nil)
+	var got []*device.CameraEvent
+	for _, data := range [][]byte{
+		camera(start, "th1", "STARTED", EventCameraMotion),
+		camera(start.Add(5*time.Second), "th1", "UPDATED", EventCameraPerson),
+		camera(start.Add(30*time.Second), "th1", "ENDED", EventCameraPerson),
+		camera(start.Add(time.Minute), "th2", "STARTED", EventCameraSound),
+		camera(start.Add(2*time.Minute), "th3", "STARTED", EventDoorbellChime),
+	} {
+		events, err := in.Ingest(data)
+		assert.NoError(t, err)
+		got = append(got, cameraEventsFrom(t, events)...)
+	}
+
+	if assert.Len(t, got, 6) {
+		assert.Equal(t, &device.CameraEvent{HasMotion: true, StartTime: start}, got[0])
+		assert.Equal(t, &device.CameraEvent{HasMotion: true, HasPerson: true, StartTime: start}, got[1])
+		assert.Equal(t, &device.CameraEvent{HasMotion: true, HasPerson: true, StartTime: start, EndTime: start.Add(30 * time.Second)}, got[2])
+		assert.Equal(t, &device.CameraEvent{HasSound: true, StartTime: start.Add(time.Minute)}, got[3])
+		// a new thread ends the previous one
+		assert.Equal(t, &device.CameraEvent{HasSound: true, StartTime: start.Add(time.Minute), EndTime: start.Add(2 * time.Minute)}, got[4])
+		assert.Equal(t, &device.CameraEvent{StartTime: start.Add(2 * time.Minute)}, got[5])
+	}
+}
@@ -0,0 +1,295 @@
+package sdm
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PubSubURL provides the default Cloud Pub/Sub REST API endpoint.
+const PubSubURL = "https://pubsub.googleapis.com/v1"
+
+// Message is a Pub/Sub message carrying an SDM event in Data.
+type Message struct {
+	AckID       string    `json:"-"`
+	ID          string    `json:"messageId"`
+	Data        []byte    `json:"data"`
+	PublishTime time.Time `json:"publishTime"`
+}
+
+// Puller receives messages from a Pub/Sub pull subscription. Pull blocks until
+// messages are available or ctx is done. Messages that are not acknowledged
+// are redelivered.
+type Puller interface {
+	Pull(ctx context.Context) ([]Message, error)
+	Ack(ctx context.Context, ackIDs []string) error
+}
+
+// Verifier authenticates a Pub/Sub push request, returning an error when the
+// request was not sent by the subscription. It may verify the OIDC token
+// Pub/Sub sends in the Authorization header, or a secret token added to the
+// push endpoint URL as with VerifyToken.
+type Verifier func(r *http.Request) error
+
+// VerifyToken returns a Verifier accepting requests whose "token" query
+// parameter equals token. Configure the push endpoint of the subscription with
+// the token, e.g. "https://example.com/pubsub/nest?token=[TOKEN]".
+func VerifyToken(token string) Verifier {
+	return func(r *http.Request) error {
+		got := r.URL.Query().Get("token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return errors.New("sdm: invalid push token")
+		}
+		return nil
+	}
+}
+
+// Handler returns an HTTP handler for a Pub/Sub push subscription. Ingested
+// events are delivered to the events channel before the message is
+// acknowledged; messages that cannot be decoded are acknowledged and dropped,
+// since redelivery would not help.
+//
+// Requests rejected by verify are answered with 401 Unauthorized. A push
+// endpoint is reachable by anyone who knows its URL, so without a verifier
+// forged requests can inject device updates and camera events; verify should
+// only be nil when the endpoint is authenticated in another way.
+func (in *Ingester) Handler(verify Verifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if verify != nil {
+			if err := verify(r); err != nil {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		var push struct {
+			Message      Message `json:"message"`
+			Subscription string  `json:"subscription"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+			http.Error(w, "invalid push message", http.StatusBadRequest)
+			return
+		}
+		events, err := in.Ingest(push.Message.Data)
+		if err != nil {
+			log.Printf("error ingesting message %s: %v\n", push.Message.ID, err)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if !in.send(r.Context().Done(), events) {
+			// not acknowledged, so Pub/Sub redelivers the message
+			http.Error(w, "not delivered", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Pull ingests messages from p until ctx is done or the ingester is closed.
+// Messages are acknowledged once their events are delivered. Errors are logged.
+func (in *Ingester) Pull(ctx context.Context, p Puller) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-in.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		msgs, err := p.Pull(ctx)
+		if ctx.Err() != nil {
+			return parent.Err()
+		}
+		if err != nil {
+			log.Printf("error pulling messages: %v\n", err)
+			if sleep(ctx, time.Second) != nil {
+				return parent.Err()
+			}
+			continue
+		}
+
+		var ackIDs []string
+		for _, m := range msgs {
+			events, err := in.Ingest(m.Data)
+			if err != nil {
+				log.Printf("error ingesting message %s: %v\n", m.ID, err)
+			} else if !in.send(ctx.Done(), events) {
+				break
+			}
+			ackIDs = append(ackIDs, m.AckID)
+		}
+		if len(ackIDs) > 0 {
+			// acknowledge delivered messages even when stopping
+			if err := p.Ack(parent, ackIDs); err != nil {
+				log.Printf("error acknowledging messages: %v\n", err)
+			}
+		}
+	}
+}
+
+// PullSubscription pulls messages from a Cloud Pub/Sub subscription with the
+// REST API. The HTTP client is expected to handle OAuth2 for the Google
+// Cloud project.
+type PullSubscription struct {
+	client      *http.Client
+	url         string
+	MaxMessages int
+}
+
+// NewPullSubscription creates a puller for the subscription with the given
+// resource name, e.g. "projects/my-project/subscriptions/nest-events".
+func NewPullSubscription(client *http.Client, subscription string) *PullSubscription {
+	return &PullSubscription{client: client, url: PubSubURL + "/" + subscription, MaxMessages: 100}
+}
+
+// Pull receives up to MaxMessages messages.
+// See https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.subscriptions/pull
+//
+func (s *PullSubscription) Pull(ctx context.Context) ([]Message, error) {
+	var resp struct {
+		ReceivedMessages []struct {
+			AckID   string  `json:"ackId"`
+			Message Message `json:"message"`
+		} `json:"receivedMessages"`
+	}
+	if err := s.post(ctx, ":pull", map[string]interface{}{"maxMessages": s.MaxMessages}, &resp); err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, len(resp.ReceivedMessages))
+	for i, rm := range resp.ReceivedMessages {
+		msgs[i] = rm.Message
+		msgs[i].AckID = rm.AckID
+	}
+	return msgs, nil
+}
+
+// Ack acknowledges messages so they are not redelivered.
+// See https://cloud.google.com/pubsub/docs/reference/rest/v1/projects.subscriptions/acknowledge
+//
+func (s *PullSubscription) Ack(ctx context.Context, ackIDs []string) error {
+	return s.post(ctx, ":acknowledge", map[string]interface{}{"ackIds": ackIDs}, nil)
+}
+
+func (s *PullSubscription) post(ctx context.Context, method string, body interface{}, v interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url+method, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// FakePuller is an in-memory Puller for tests. Published messages are
+// delivered by Pull and redelivered until they are acknowledged.
+type FakePuller struct {
+	mu      sync.Mutex
+	next    int
+	pending []Message
+	acked   []string
+	notify  chan struct{}
+}
+
+// NewFakePuller creates an empty fake subscription.
+func NewFakePuller() *FakePuller {
+	return &FakePuller{notify: make(chan struct{}, 1)}
+}
+
+// Publish adds a message with the given data and returns its id.
+func (f *FakePuller) Publish(data []byte) string {
+	f.mu.Lock()
+	f.next++
+	id := strconv.Itoa(f.next)
+	f.pending = append(f.pending, Message{AckID: "ack-" + id, ID: id, Data: data, PublishTime: time.Now()})
+	f.mu.Unlock()
+
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+	return id
+}
+
+// Pull returns every unacknowledged message, blocking until there is one.
+func (f *FakePuller) Pull(ctx context.Context) ([]Message, error) {
+	for {
+		f.mu.Lock()
+		msgs := append([]Message(nil), f.pending...)
+		f.mu.Unlock()
+		if len(msgs) > 0 {
+			return msgs, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-f.notify:
+		}
+	}
+}
+
+// Ack acknowledges messages.
+func (f *FakePuller) Ack(ctx context.Context, ackIDs []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	acked := map[string]bool{}
+	for _, id := range ackIDs {
+		acked[id] = true
+	}
+	pending := f.pending[:0]
+	for _, m := range f.pending {
+		if acked[m.AckID] {
+			f.acked = append(f.acked, m.ID)
+			continue
+		}
+		pending = append(pending, m)
+	}
+	f.pending = pending
+	return nil
+}
+
+// Acked returns the ids of the acknowledged messages in order.
+func (f *FakePuller) Acked() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acked...)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+package sdm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/stretchr/testify/assert"
+)
+
+const humidityUpdate = `{"resourceUpdate": {"name": "enterprises/project-1/devices/t1",
+	"traits": {"sdm.devices.traits.ThermostatMode": {"mode": "HEAT"}, "sdm.devices.traits.Humidity": {"ambientHumidityPercent": %d}}}}`
+
+func Test_Handler(t *testing.T) {
+	in := NewIngester(nil)
+	defer in.Close()
+	ts := httptest.NewServer(in.Handler(VerifyToken("s3cret")))
+	defer ts.Close()
+
+	push := func(body string) int {
+		resp, err := http.Post(ts.URL+"?token=s3cret", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	envelope := func(data string) string {
+		return fmt.Sprintf(`{"message": {"data": %q, "messageId": "1"}, "subscription": "projects/p/subscriptions/s"}`,
+			base64.StdEncoding.EncodeToString([]byte(data)))
+	}
+
+	done := make(chan nest.Event)
+	go func() { done <- <-in.Events() }()
+	assert.Equal(t, http.StatusNoContent, push(envelope(fmt.Sprintf(humidityUpdate, 55))))
+	assert.Equal(t, 55, thermostatFrom(t, []nest.Event{<-done}).Humidity)
+
+	assert.Equal(t, http.StatusBadRequest, push("not json"))
+	assert.Equal(t, http.StatusNoContent, push(envelope("not an sdm event")), "undecodable events should be acknowledged")
+
+	// events that cannot be delivered are not acknowledged
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"?token=s3cret", bytes.NewBufferString(envelope(fmt.Sprintf(humidityUpdate, 60))))
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err == nil {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
+
+func Test_HandlerVerify(t *testing.T) {
+	in := NewIngester(nil)
+	defer in.Close()
+	ts := httptest.NewServer(in.Handler(VerifyToken("s3cret")))
+	defer ts.Close()
+
+	for _, u := range []string{ts.URL, ts.URL + "?token=forged"} {
+		resp, err := http.Post(u, "application/json", bytes.NewBufferString(`{"message": {}}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	assert.Error(t, VerifyToken("")(httptest.NewRequest(http.MethodPost, "/?token=", nil)), "an empty token should not verify")
+}
+
+func Test_Pull(t *testing.T) {
+	p := NewFakePuller()
+	p.Publish([]byte(fmt.Sprintf(humidityUpdate, 40)))
+	p.Publish([]byte("not an sdm event"))
+	p.Publish([]byte(fmt.Sprintf(humidityUpdate, 45)))
+
+	in := NewIngester(nil)
+	errs := make(chan error, 1)
+	go func() { errs <- in.Pull(context.Background(), p) }()
+
+	var humidity []int
+	for len(humidity) < 2 {
+		humidity = append(humidity, thermostatFrom(t, []nest.Event{<-in.Events()}).Humidity)
+	}
+	assert.Equal(t, []int{40, 45}, humidity)
+
+	assert.NoError(t, in.Close())
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []string{"1", "2", "3"}, p.Acked())
+	_, ok := <-in.Events()
+	assert.False(t, ok)
+}
+
+func Test_PullSubscription(t *testing.T) {
+	var acked []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/p/subscriptions/s:pull":
+			fmt.Fprintf(w, `{"receivedMessages": [{"ackId": "a1", "message": {"data": %q, "messageId": "m1", "publishTime": "2020-03-24T11:00:00Z"}}]}`,
+				base64.StdEncoding.EncodeToString([]byte("{}")))
+		case "/projects/p/subscriptions/s:acknowledge":
+			var body struct {
+				AckIDs []string `json:"ackIds"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			acked = body.AckIDs
+			fmt.Fprint(w, "{}")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	s := NewPullSubscription(ts.Client(), "projects/p/subscriptions/s")
+	s.url = ts.URL + "/projects/p/subscriptions/s"
+	msgs, err := s.Pull(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Message{{AckID: "a1", ID: "m1", Data: []byte("{}"), PublishTime: time.Date(2020, 3, 24, 11, 0, 0, 0, time.UTC)}}, msgs)
+	assert.NoError(t, s.Ack(context.Background(), []string{"a1"}))
+	assert.Equal(t, []string{"a1"}, acked)
+}
@@ -0,0 +1,72 @@
+package sdm
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// Structure trait names
+const (
+	TraitStructureInfo = "sdm.structures.traits.Info"
+	TraitRoomInfo      = "sdm.structures.traits.RoomInfo"
+)
+
+// StructureService reads structures. Its methods mirror nest.StructureService.
+type StructureService service
+
+type resource struct {
+	Name   string `json:"name"`
+	Traits struct {
+		Structure *Info `json:"sdm.structures.traits.Info"`
+		Room      *Info `json:"sdm.structures.traits.RoomInfo"`
+	} `json:"traits"`
+}
+
+// Get fetches a structure given its id or resource name. The rooms of the
+// structure are returned as its wheres. SDM does not provide the away state,
+// time zone or postal code of a structure.
+// See https://developers.google.com/nest/device-access/reference/rest/v1/enterprises.structures/get
+//
+func (svc *StructureService) Get(structureid string) (*device.Structure, error) {
+	path := structureid
+	if !strings.HasPrefix(path, "enterprises/") {
+		path = svc.client.projectPath("structures/" + structureid)
+	}
+	var s resource
+	if err := svc.client.do(http.MethodGet, path, nil, &s); err != nil {
+		return nil, err
+	}
+	var rooms struct {
+		Rooms []resource `json:"rooms"`
+	}
+	if err := svc.client.do(http.MethodGet, path+"/rooms", nil, &rooms); err != nil {
+		return nil, err
+	}
+
+	st := &device.Structure{
+		StructureID: lastSegment(s.Name),
+		Wheres:      map[string]*device.Where{},
+	}
+	if s.Traits.Structure != nil {
+		st.Name = s.Traits.Structure.CustomName
+	}
+	for _, r := range rooms.Rooms {
+		w := &device.Where{WhereID: lastSegment(r.Name)}
+		if r.Traits.Room != nil {
+			w.Name = r.Traits.Room.CustomName
+		}
+		st.Wheres[w.WhereID] = w
+	}
+	return st, nil
+}
+
+// SmokeCoAlarmService mirrors nest.SmokeCoAlarmService. The SDM API does not
+// provide Smoke+CO Alarms.
+type SmokeCoAlarmService service
+
+// Get is not supported by the SDM API.
+func (svc *SmokeCoAlarmService) Get(deviceid string) (*device.SmokeAlarm, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,47 @@
+package sdm
+
+import (
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StructureGet(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+	c := newTestClient(t, f)
+
+	for _, id := range []string{"s1", "enterprises/project-1/structures/s1"} {
+		s, err := c.Structures.Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, &device.Structure{
+			StructureID: "s1",
+			Name:        "Home",
+			Wheres: map[string]*device.Where{
+				"r1": {WhereID: "r1", Name: "Living Room"},
+				"r2": {WhereID: "r2", Name: "Entryway"},
+			},
+		}, s)
+	}
+
+	_, err := c.Structures.Get("unknown")
+	assert.EqualError(t, err, "Device not found.")
+}
+
+func Test_Backend(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+	b := newTestClient(t, f).Backend()
+
+	devices, err := b.Devices.Devices()
+	assert.NoError(t, err)
+	assert.Len(t, devices.Thermostats, 1)
+
+	th, err := b.Thermostats.Get("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, device.HvacModeHeat, th.HvacMode)
+
+	_, err = b.SmokeCoAlarms.Get("p1")
+	assert.Equal(t, ErrUnsupported, err)
+}
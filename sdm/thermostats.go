@@ -0,0 +1,178 @@
+package sdm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+)
+
+// ThermostatService controls thermostats through SDM trait commands. Its
+// methods mirror nest.ThermostatService.
+type ThermostatService service
+
+// SetTargetTemperature changes the heat or cool setpoint, depending on the
+// thermostat's mode. Fahrenheit targets are converted to Celsius, the only
+// scale SDM accepts.
+// See https://developers.google.com/nest/device-access/traits/device/thermostat-temperature-setpoint
+//
+func (svc *ThermostatService) SetTargetTemperature(deviceid string, scale device.TemperatureScale, target int) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	d, err := svc.client.Device(deviceid)
+	if err != nil {
+		return err
+	}
+	var mode ThermostatMode
+	if err := d.Trait(TraitThermostatMode, &mode); err != nil {
+		return err
+	}
+	switch mode.Mode {
+	case "HEAT":
+		return svc.command(deviceid, "ThermostatTemperatureSetpoint.SetHeat", map[string]interface{}{
+			"heatCelsius": toCelsius(scale, target),
+		})
+	case "COOL":
+		return svc.command(deviceid, "ThermostatTemperatureSetpoint.SetCool", map[string]interface{}{
+			"coolCelsius": toCelsius(scale, target),
+		})
+	}
+	return fmt.Errorf("cannot set target temperature in %s mode", hvacMode(mode.Mode))
+}
+
+// SetTargetTemperatureRange changes the heat and cool setpoints of a thermostat
+// in heat-cool mode.
+// See https://developers.google.com/nest/device-access/traits/device/thermostat-temperature-setpoint#setrange
+//
+func (svc *ThermostatService) SetTargetTemperatureRange(deviceid string, scale device.TemperatureScale, low, high int) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
+	if low == 0 && high == 0 {
+		return errors.New("either low or high target must be set above 0")
+	}
+	if low >= high {
+		return errors.New("low value must be less than or equal to high value")
+	}
+	return svc.command(deviceid, "ThermostatTemperatureSetpoint.SetRange", map[string]interface{}{
+		"heatCelsius": toCelsius(scale, low),
+		"coolCelsius": toCelsius(scale, high),
+	})
+}
+
+// SetHVACMode sets the thermostat mode. Eco mode is set with the ThermostatEco
+// trait; setting any other mode turns manual eco off first.
+// See https://developers.google.com/nest/device-access/traits/device/thermostat-mode
+//
+func (svc *ThermostatService) SetHVACMode(deviceid string, state device.HvacMode) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
+	if state == device.HvacModeEco {
+		return svc.EnterEco(deviceid)
+	}
+	if err := svc.ExitEco(deviceid); err != nil {
+		return err
+	}
+	return svc.command(deviceid, "ThermostatMode.SetMode", map[string]interface{}{
+		"mode": sdmMode(state),
+	})
+}
+
+// EnterEco switches the thermostat to manual Eco Temperatures.
+// See https://developers.google.com/nest/device-access/traits/device/thermostat-eco
+//
+func (svc *ThermostatService) EnterEco(deviceid string) error {
+	return svc.command(deviceid, "ThermostatEco.SetMode", map[string]interface{}{"mode": "MANUAL_ECO"})
+}
+
+// ExitEco turns manual Eco Temperatures off, restoring the thermostat mode.
+func (svc *ThermostatService) ExitEco(deviceid string) error {
+	d, err := svc.client.Device(deviceid)
+	if err != nil {
+		return err
+	}
+	if !d.HasTrait(TraitThermostatEco) {
+		return nil
+	}
+	var eco ThermostatEco
+	if err := d.Trait(TraitThermostatEco, &eco); err != nil {
+		return err
+	}
+	if !eco.On() {
+		return nil
+	}
+	return svc.command(deviceid, "ThermostatEco.SetMode", map[string]interface{}{"mode": "OFF"})
+}
+
+// SetFanTimerDuration runs the fan for the given number of minutes.
+// See https://developers.google.com/nest/device-access/traits/device/fan#settimer
+//
+func (svc *ThermostatService) SetFanTimerDuration(deviceid string, duration int) error {
+	if duration%15 != 0 {
+		return errors.New("duration must be a multiple of 15")
+	}
+	return svc.command(deviceid, "Fan.SetTimer", map[string]interface{}{
+		"timerMode": "ON",
+		"duration":  fmt.Sprintf("%ds", duration*60),
+	})
+}
+
+// GetFanTimerActive fetches the thermostat. Use Get and FanTimerActive to read
+// the fan timer state.
+func (svc *ThermostatService) GetFanTimerActive(deviceid string) error {
+	_, err := svc.client.Device(deviceid)
+	return err
+}
+
+// SetLabel is not supported by the SDM API.
+func (svc *ThermostatService) SetLabel(deviceid string, label string) error {
+	return ErrUnsupported
+}
+
+// SetTemperatureScale is not supported by the SDM API, where the display scale
+// is read-only.
+func (svc *ThermostatService) SetTemperatureScale(deviceid string, scale device.TemperatureScale) error {
+	return ErrUnsupported
+}
+
+// Get fetches a thermostat given its device id or resource name, converted
+// from its SDM traits.
+func (svc *ThermostatService) Get(deviceid string) (*device.Thermostat, error) {
+	d, err := svc.client.Device(deviceid)
+	if err != nil {
+		return nil, err
+	}
+	return d.Thermostat()
+}
+
+// Stream is not supported by the SDM API, which delivers events through
+// Cloud Pub/Sub.
+func (svc *ThermostatService) Stream(deviceID string) (*nest.Stream, error) {
+	return nil, ErrUnsupported
+}
+
+func (svc *ThermostatService) command(deviceid, command string, params map[string]interface{}) error {
+	return svc.client.ExecuteCommand(deviceid, "sdm.devices.commands."+command, params, nil)
+}
+
+func sdmMode(mode device.HvacMode) string {
+	switch mode {
+	case device.HvacModeHeat:
+		return "HEAT"
+	case device.HvacModeCool:
+		return "COOL"
+	case device.HvacModeHeatCool:
+		return "HEATCOOL"
+	}
+	return "OFF"
+}
+
+func toCelsius(scale device.TemperatureScale, t int) float64 {
+	if scale == device.Fahrenheit {
+		return celsius(t)
+	}
+	return float64(t)
+}
@@ -0,0 +1,130 @@
+package sdm
+
+import (
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ThermostatGet(t *testing.T) {
+	f := newFakeSDM()
+	defer f.Close()
+
+	th, err := newTestClient(t, f).Thermostats.Get("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", th.DeviceID)
+	assert.Equal(t, "s1", th.StructureID)
+	assert.True(t, th.IsOnline)
+	assert.Equal(t, 42, th.Humidity)
+	assert.Equal(t, 20.5, th.AmbientTemperatureC)
+	assert.Equal(t, 69, th.AmbientTemperatureF)
+	assert.Equal(t, device.Fahrenheit, th.TemperatureScale)
+	assert.Equal(t, device.HvacModeHeat, th.HvacMode)
+	assert.Equal(t, device.HvacStateHeating, th.HvacState)
+	assert.True(t, th.CanHeat && th.CanCool && th.HasFan)
+	assert.Equal(t, 21.0, th.TargetTemperatureC)
+	assert.Equal(t, 70, th.TargetTemperatureF)
+	assert.Equal(t, 60, th.EcoTemperatureLowF)
+
+	f.eco = "MANUAL_ECO"
+	th, err = newTestClient(t, f).Thermostats.Get("enterprises/project-1/devices/t1")
+	assert.NoError(t, err)
+	assert.Equal(t, device.HvacModeEco, th.HvacMode)
+	assert.Equal(t, device.HvacModeHeat, th.PreviousHvacMode)
+
+	_, err = newTestClient(t, f).Thermostats.Get("c1")
+	assert.EqualError(t, err, "device c1 is not a thermostat")
+}
+
+func Test_ThermostatCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		eco     string
+		call    func(svc *ThermostatService) error
+		want    []command
+		wantErr string
+	}{
+		{
+			"target temperature in heat mode",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetTargetTemperature("t1", device.Fahrenheit, 68) },
+			[]command{{"t1", "sdm.devices.commands.ThermostatTemperatureSetpoint.SetHeat", map[string]interface{}{"heatCelsius": 20.0}}},
+			"",
+		},
+		{
+			"target range",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetTargetTemperatureRange("t1", device.Celsius, 19, 24) },
+			[]command{{"t1", "sdm.devices.commands.ThermostatTemperatureSetpoint.SetRange", map[string]interface{}{"heatCelsius": 19.0, "coolCelsius": 24.0}}},
+			"",
+		},
+		{
+			"invalid range",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetTargetTemperatureRange("t1", device.Celsius, 24, 19) },
+			nil,
+			"low value must be less than or equal to high value",
+		},
+		{
+			"mode",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetHVACMode("t1", device.HvacModeHeatCool) },
+			[]command{{"t1", "sdm.devices.commands.ThermostatMode.SetMode", map[string]interface{}{"mode": "HEATCOOL"}}},
+			"",
+		},
+		{
+			"mode leaves eco",
+			"MANUAL_ECO",
+			func(svc *ThermostatService) error { return svc.SetHVACMode("t1", device.HvacModeCool) },
+			[]command{
+				{"t1", "sdm.devices.commands.ThermostatEco.SetMode", map[string]interface{}{"mode": "OFF"}},
+				{"t1", "sdm.devices.commands.ThermostatMode.SetMode", map[string]interface{}{"mode": "COOL"}},
+			},
+			"",
+		},
+		{
+			"eco",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetHVACMode("t1", device.HvacModeEco) },
+			[]command{{"t1", "sdm.devices.commands.ThermostatEco.SetMode", map[string]interface{}{"mode": "MANUAL_ECO"}}},
+			"",
+		},
+		{
+			"invalid mode",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetHVACMode("t1", "blah") },
+			nil,
+			`invalid hvac mode: "blah"`,
+		},
+		{
+			"fan timer",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetFanTimerDuration("t1", 30) },
+			[]command{{"t1", "sdm.devices.commands.Fan.SetTimer", map[string]interface{}{"timerMode": "ON", "duration": "1800s"}}},
+			"",
+		},
+		{
+			"label",
+			"OFF",
+			func(svc *ThermostatService) error { return svc.SetLabel("t1", "Upstairs") },
+			nil,
+			ErrUnsupported.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSDM()
+			defer f.Close()
+			f.eco = tt.eco
+
+			err := tt.call(newTestClient(t, f).Thermostats)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, f.commands)
+		})
+	}
+}
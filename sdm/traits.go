@@ -0,0 +1,145 @@
+package sdm
+
+import "time"
+
+// SDM device trait names
+// See https://developers.google.com/nest/device-access/traits
+const (
+	TraitInfo                          = "sdm.devices.traits.Info"
+	TraitConnectivity                  = "sdm.devices.traits.Connectivity"
+	TraitHumidity                      = "sdm.devices.traits.Humidity"
+	TraitTemperature                   = "sdm.devices.traits.Temperature"
+	TraitSettings                      = "sdm.devices.traits.Settings"
+	TraitFan                           = "sdm.devices.traits.Fan"
+	TraitThermostatMode                = "sdm.devices.traits.ThermostatMode"
+	TraitThermostatEco                 = "sdm.devices.traits.ThermostatEco"
+	TraitThermostatHvac                = "sdm.devices.traits.ThermostatHvac"
+	TraitThermostatTemperatureSetpoint = "sdm.devices.traits.ThermostatTemperatureSetpoint"
+	TraitCameraLiveStream              = "sdm.devices.traits.CameraLiveStream"
+	TraitCameraImage                   = "sdm.devices.traits.CameraImage"
+	TraitCameraEventImage              = "sdm.devices.traits.CameraEventImage"
+	TraitCameraMotion                  = "sdm.devices.traits.CameraMotion"
+	TraitCameraPerson                  = "sdm.devices.traits.CameraPerson"
+	TraitCameraSound                   = "sdm.devices.traits.CameraSound"
+	TraitDoorbellChime                 = "sdm.devices.traits.DoorbellChime"
+)
+
+// Traits holds the typed traits of a device. Traits the device does not
+// support are nil.
+type Traits struct {
+	Info                          *Info                          `json:"sdm.devices.traits.Info,omitempty"`
+	Connectivity                  *Connectivity                  `json:"sdm.devices.traits.Connectivity,omitempty"`
+	Humidity                      *Humidity                      `json:"sdm.devices.traits.Humidity,omitempty"`
+	Temperature                   *Temperature                   `json:"sdm.devices.traits.Temperature,omitempty"`
+	Settings                      *Settings                      `json:"sdm.devices.traits.Settings,omitempty"`
+	Fan                           *Fan                           `json:"sdm.devices.traits.Fan,omitempty"`
+	ThermostatMode                *ThermostatMode                `json:"sdm.devices.traits.ThermostatMode,omitempty"`
+	ThermostatEco                 *ThermostatEco                 `json:"sdm.devices.traits.ThermostatEco,omitempty"`
+	ThermostatHvac                *ThermostatHvac                `json:"sdm.devices.traits.ThermostatHvac,omitempty"`
+	ThermostatTemperatureSetpoint *ThermostatTemperatureSetpoint `json:"sdm.devices.traits.ThermostatTemperatureSetpoint,omitempty"`
+	CameraLiveStream              *CameraLiveStream              `json:"sdm.devices.traits.CameraLiveStream,omitempty"`
+	CameraImage                   *CameraImage                   `json:"sdm.devices.traits.CameraImage,omitempty"`
+	CameraEventImage              *struct{}                      `json:"sdm.devices.traits.CameraEventImage,omitempty"`
+	CameraMotion                  *struct{}                      `json:"sdm.devices.traits.CameraMotion,omitempty"`
+	CameraPerson                  *struct{}                      `json:"sdm.devices.traits.CameraPerson,omitempty"`
+	CameraSound                   *struct{}                      `json:"sdm.devices.traits.CameraSound,omitempty"`
+	DoorbellChime                 *struct{}                      `json:"sdm.devices.traits.DoorbellChime,omitempty"`
+}
+
+// Info holds the user-defined name of a device.
+type Info struct {
+	CustomName string `json:"customName"`
+}
+
+// Connectivity holds whether a device is connected to the network.
+type Connectivity struct {
+	Status string `json:"status"`
+}
+
+// Online reports whether the device is online.
+func (c *Connectivity) Online() bool {
+	return c.Status == "ONLINE"
+}
+
+// Humidity holds the ambient humidity measured by a device.
+type Humidity struct {
+	AmbientHumidityPercent float64 `json:"ambientHumidityPercent"`
+}
+
+// Temperature holds the ambient temperature measured by a device.
+type Temperature struct {
+	AmbientTemperatureCelsius float64 `json:"ambientTemperatureCelsius"`
+}
+
+// Settings holds the display settings of a device. TemperatureScale is
+// CELSIUS or FAHRENHEIT.
+type Settings struct {
+	TemperatureScale string `json:"temperatureScale"`
+}
+
+// Fan holds the fan timer of a thermostat. TimerMode is ON or OFF.
+type Fan struct {
+	TimerMode    string    `json:"timerMode"`
+	TimerTimeout time.Time `json:"timerTimeout"`
+}
+
+// ThermostatMode holds the mode of a thermostat: HEAT, COOL, HEATCOOL or OFF.
+type ThermostatMode struct {
+	Mode           string   `json:"mode"`
+	AvailableModes []string `json:"availableModes"`
+}
+
+// Supports reports whether mode is one of the available modes.
+func (m *ThermostatMode) Supports(mode string) bool {
+	for _, available := range m.AvailableModes {
+		if available == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ThermostatEco holds the eco mode of a thermostat, OFF or MANUAL_ECO, and its
+// eco temperature range.
+type ThermostatEco struct {
+	AvailableModes []string `json:"availableModes"`
+	Mode           string   `json:"mode"`
+	HeatCelsius    float64  `json:"heatCelsius"`
+	CoolCelsius    float64  `json:"coolCelsius"`
+}
+
+// On reports whether manual eco mode is on.
+func (e *ThermostatEco) On() bool {
+	return e.Mode == "MANUAL_ECO"
+}
+
+// ThermostatHvac holds the HVAC status of a thermostat: OFF, HEATING or COOLING.
+type ThermostatHvac struct {
+	Status string `json:"status"`
+}
+
+// ThermostatTemperatureSetpoint holds the heat and cool setpoints of a
+// thermostat. Only the setpoints used by the current mode are set.
+type ThermostatTemperatureSetpoint struct {
+	HeatCelsius float64 `json:"heatCelsius,omitempty"`
+	CoolCelsius float64 `json:"coolCelsius,omitempty"`
+}
+
+// CameraLiveStream describes the live streams a camera can generate.
+type CameraLiveStream struct {
+	MaxVideoResolution Resolution `json:"maxVideoResolution"`
+	VideoCodecs        []string   `json:"videoCodecs"`
+	AudioCodecs        []string   `json:"audioCodecs"`
+	SupportedProtocols []string   `json:"supportedProtocols"`
+}
+
+// CameraImage describes the images a camera can take.
+type CameraImage struct {
+	MaxImageResolution Resolution `json:"maxImageResolution"`
+}
+
+// Resolution is the size of an image or video.
+type Resolution struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
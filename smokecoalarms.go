@@ -36,7 +36,7 @@ func (svc *SmokeCoAlarmService) Get(deviceid string) (*device.SmokeAlarm, error)
 //
 func (svc *SmokeCoAlarmService) Stream(deviceID string) (*Stream, error) {
 	rel := &url.URL{Path: fmt.Sprintf("%s/%s", svc.apiURL.String(), deviceID)}
-	return NewStream(&config.Config{
+	return svc.client.trackStream(NewStream(&config.Config{
 		APIURL: svc.client.baseURL.ResolveReference(rel).String(),
-	}, svc.client.httpClient)
+	}, svc.client.httpClient))
 }
@@ -0,0 +1,29 @@
+package nest
+
+import (
+	"net/url"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// StructureService reads structure data such as the away state and time zone.
+type StructureService service
+
+// NewStructureService creates a new service to interact with structures.
+func NewStructureService(client *Client) *StructureService {
+	u := &url.URL{Path: "/structures"}
+
+	return &StructureService{
+		client: client,
+		apiURL: u,
+	}
+}
+
+// Get fetches an updated structure object given a structure id.
+// https://developers.nest.com/reference/api-structure
+//
+func (svc *StructureService) Get(structureid string) (*device.Structure, error) {
+	var structure device.Structure
+	err := svc.client.getDevice(structureid, svc.apiURL.String(), &structure)
+	return &structure, err
+}
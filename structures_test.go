@@ -0,0 +1,50 @@
+package nest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+const structureResponse = `{
+	"smoke_co_alarms": [
+		"2y2eUoaaXxBij4O1rxoiGfVfehTNCJA_"
+	],
+	"name": "Home 1",
+	"country_code": "US",
+	"time_zone": "America/Los_Angeles",
+	"away": "home",
+	"thermostats": [
+		"JP2FgJUZqqAXUBfYYWVUY_VfehTNCJA_",
+		"JP2FgJUZqqBppN16wdLGxvVfehTNCJA_"
+	],
+	"structure_id": "xYylA-lypQl5FHuJj2pY_JU3k-aKvEOT3oUEV_Nu8u85w_hO-s4xRg",
+	"co_alarm_state": "ok",
+	"smoke_alarm_state": "ok",
+	"wheres": {
+		"osefycV5UZDoYWGlEtfvxzW9zlfCGfY3qyS_RoiaCCn9-0TRb0IF3w": {
+			"where_id": "osefycV5UZDoYWGlEtfvxzW9zlfCGfY3qyS_RoiaCCn9-0TRb0IF3w",
+			"name": "Attic"
+		}
+	}
+}`
+
+func Test_GetStructure(t *testing.T) {
+	c := newTestClient(structureResponse, http.StatusOK)
+	s := NewStructureService(c)
+	st, err := s.Get("xYylA-lypQl5FHuJj2pY_JU3k-aKvEOT3oUEV_Nu8u85w_hO-s4xRg")
+	if err != nil {
+		assert.Fail(t, err.Error())
+		return
+	}
+
+	assert.Equal(t, "xYylA-lypQl5FHuJj2pY_JU3k-aKvEOT3oUEV_Nu8u85w_hO-s4xRg", st.StructureID, "structureIDs should match")
+	assert.Equal(t, device.AwayStateHome, st.Away)
+	assert.Len(t, st.Thermostats, 2)
+
+	loc, err := st.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, "America/Los_Angeles", loc.String())
+}
@@ -11,28 +11,26 @@ import (
 	"github.com/jtsiros/nest/device"
 )
 
-type hvacMode string
-
+// Supported HVAC modes. See device.HvacMode.
 const (
 	// Heat mode
-	Heat hvacMode = "heat"
+	Heat = device.HvacModeHeat
 	// Cool mode
-	Cool hvacMode = "cool"
+	Cool = device.HvacModeCool
 	// HeatCool mode
-	HeatCool hvacMode = "heat-cool"
+	HeatCool = device.HvacModeHeatCool
 	// Eco mode
-	Eco hvacMode = "eco"
+	Eco = device.HvacModeEco
 	// Off mode
-	Off hvacMode = "off"
+	Off = device.HvacModeOff
 )
 
-type tempScale string
-
+// Supported temperature scales. See device.TemperatureScale.
 const (
 	// F represents Farenheight
-	F tempScale = "F"
+	F = device.Fahrenheit
 	// C represents celcius
-	C tempScale = "C"
+	C = device.Celsius
 )
 
 type values map[string]interface{}
@@ -53,7 +51,10 @@ func NewThermostatService(client *Client) *ThermostatService {
 // SetTargetTemperature changes the target temperature on the Thermostat.
 // See https://developers.nest.com/guides/thermostat-guide#target_temperature
 //
-func (svc *ThermostatService) SetTargetTemperature(deviceid string, scale tempScale, target int) error {
+func (svc *ThermostatService) SetTargetTemperature(deviceid string, scale device.TemperatureScale, target int) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
 	ttKey := fmt.Sprintf("target_temperature_%s", strings.ToLower(string(scale)))
 	return svc.requestWithValues(http.MethodPut, deviceid, values{ttKey: target})
 }
@@ -63,7 +64,10 @@ func (svc *ThermostatService) SetTargetTemperature(deviceid string, scale tempSc
 // target_temperature_low(f|c)
 // target_temperature_high(f|c)
 //
-func (svc *ThermostatService) SetTargetTemperatureRange(deviceid string, scale tempScale, low, high int) error {
+func (svc *ThermostatService) SetTargetTemperatureRange(deviceid string, scale device.TemperatureScale, low, high int) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
 	s := strings.ToLower(string(scale))
 	values := map[string]interface{}{}
 
@@ -89,7 +93,10 @@ func (svc *ThermostatService) SetTargetTemperatureRange(deviceid string, scale t
 //
 // See https://developers.nest.com/reference/api-thermostat#hvac_mode
 //
-func (svc *ThermostatService) SetHVACMode(deviceid string, state hvacMode) error {
+func (svc *ThermostatService) SetHVACMode(deviceid string, state device.HvacMode) error {
+	if err := state.Validate(); err != nil {
+		return err
+	}
 	return svc.requestWithValues(http.MethodPut, deviceid, values{"hvac_mode": state})
 }
 
@@ -117,7 +124,10 @@ func (svc *ThermostatService) SetLabel(deviceid string, label string) error {
 }
 
 // SetTemperatureScale sets the temperature scale display to F or C.
-func (svc *ThermostatService) SetTemperatureScale(deviceid string, scale tempScale) error {
+func (svc *ThermostatService) SetTemperatureScale(deviceid string, scale device.TemperatureScale) error {
+	if err := scale.Validate(); err != nil {
+		return err
+	}
 	return svc.requestWithValues(http.MethodPut, deviceid, values{"temperature_scale": scale})
 }
 
@@ -136,19 +146,11 @@ func (svc *ThermostatService) Get(deviceid string) (*device.Thermostat, error) {
 //
 func (svc *ThermostatService) Stream(deviceID string) (*Stream, error) {
 	rel := &url.URL{Path: fmt.Sprintf("%s/%s", svc.apiURL, deviceID)}
-	return NewStream(&config.Config{
+	return svc.client.trackStream(NewStream(&config.Config{
 		APIURL: svc.client.baseURL.ResolveReference(rel).String(),
-	}, svc.client.httpClient)
+	}, svc.client.httpClient))
 }
 
 func (svc *ThermostatService) requestWithValues(method string, path string, values map[string]interface{}) error {
-	url := fmt.Sprintf("%s/%s", svc.apiURL.String(), path)
-	req, err := svc.client.newRequest(method, url, values)
-
-	if err != nil {
-		return err
-	}
-
-	_, err = svc.client.do(req, nil)
-	return err
+	return svc.client.requestWithValues(method, fmt.Sprintf("%s/%s", svc.apiURL.String(), path), values)
 }
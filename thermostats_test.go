@@ -91,7 +91,7 @@ func Test_SetTargetTemp(t *testing.T) {
 	}
 
 	for _, tc := range tt {
-		err := tc.s.SetTargetTemperature(tc.d.DeviceID, tempScale(tc.d.TemperatureScale), tc.target)
+		err := tc.s.SetTargetTemperature(tc.d.DeviceID, tc.d.TemperatureScale, tc.target)
 		if tc.err != "" {
 			assert.Equal(t, tc.err, err.Error())
 		}
@@ -119,7 +119,7 @@ func Test_SetTargetTempRange(t *testing.T) {
 	}
 
 	for _, tc := range tt {
-		err := tc.s.SetTargetTemperatureRange(tc.d.DeviceID, tempScale(tc.d.TemperatureScale), tc.low, tc.high)
+		err := tc.s.SetTargetTemperatureRange(tc.d.DeviceID, tc.d.TemperatureScale, tc.low, tc.high)
 		if tc.err != "" {
 			assert.Equal(t, tc.err, err.Error())
 		}
@@ -131,7 +131,7 @@ func Test_SetHVACMode(t *testing.T) {
 	tt := []struct {
 		deviceID string
 		s        *ThermostatService
-		mode     hvacMode
+		mode     device.HvacMode
 		err      string
 	}{
 		{"123", NewThermostatService(newTestClient("heat", http.StatusOK)), Heat, ""},
@@ -139,7 +139,7 @@ func Test_SetHVACMode(t *testing.T) {
 		{"123", NewThermostatService(newTestClient("heat-cool", http.StatusOK)), HeatCool, ""},
 		{"123", NewThermostatService(newTestClient("eco", http.StatusOK)), Eco, ""},
 		{"123", NewThermostatService(newTestClient("off", http.StatusOK)), Eco, ""},
-		{"123", NewThermostatService(newTestClient("{\"message\":\"Invalid HVAC mode: blah\"}", http.StatusBadRequest)), hvacMode("blah"), "Invalid HVAC mode: blah"},
+		{"123", NewThermostatService(newTestClient("", http.StatusOK)), device.HvacMode("blah"), "invalid hvac mode: \"blah\""},
 		{"456", NewThermostatService(newTestClient("{\"message\":\"Invalid thermostat id: 456\"}", http.StatusBadRequest)), Cool, "Invalid thermostat id: 456"},
 	}
 
@@ -213,10 +213,10 @@ func Test_SetTempScale(t *testing.T) {
 	tt := []struct {
 		deviceID string
 		s        *ThermostatService
-		scale    tempScale
+		scale    device.TemperatureScale
 		err      string
 	}{
-		{"123", NewThermostatService(newTestClient("{\"message\":\"Unspecified error\"}", http.StatusBadRequest)), tempScale("D"), "Unspecified error"},
+		{"123", NewThermostatService(newTestClient("", http.StatusOK)), device.TemperatureScale("D"), "invalid temperature scale: \"D\""},
 		{"123", NewThermostatService(newTestClient("F", http.StatusOK)), F, ""},
 		{"123", NewThermostatService(newTestClient("C", http.StatusOK)), C, ""},
 	}
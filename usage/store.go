@@ -0,0 +1,114 @@
+package usage
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+)
+
+// Total is the time a thermostat spent heating and cooling on a single day.
+type Total struct {
+	DeviceID string        `json:"device_id"`
+	Day      time.Time     `json:"day"`
+	Heating  time.Duration `json:"heating"`
+	Cooling  time.Duration `json:"cooling"`
+}
+
+// DutyCycle returns the fraction of the day spent heating and cooling. Days
+// are 23 or 25 hours long when daylight saving time starts or ends.
+func (t Total) DutyCycle() (heating, cooling float64) {
+	length := t.Day.AddDate(0, 0, 1).Sub(t.Day)
+	return float64(t.Heating) / float64(length), float64(t.Cooling) / float64(length)
+}
+
+// Store persists runtime totals.
+type Store interface {
+	// Add adds d to the total for state on the device's day.
+	Add(deviceID string, day time.Time, state device.HvacState, d time.Duration) error
+	// Totals returns the totals of every device for days starting within [from, to),
+	// ordered by day and device id.
+	Totals(from, to time.Time) ([]Total, error)
+}
+
+// MemoryStore keeps totals in memory.
+type MemoryStore struct {
+	mu     sync.Mutex
+	totals map[string]*Total
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{totals: map[string]*Total{}}
+}
+
+// Add adds d to the total for state on the device's day.
+func (m *MemoryStore) Add(deviceID string, day time.Time, state device.HvacState, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := deviceID + "/" + day.Format("2006-01-02")
+	t, ok := m.totals[key]
+	if !ok {
+		t = &Total{DeviceID: deviceID, Day: day}
+		m.totals[key] = t
+	}
+	switch state {
+	case device.HvacStateHeating:
+		t.Heating += d
+	case device.HvacStateCooling:
+		t.Cooling += d
+	}
+	return nil
+}
+
+// Totals returns the totals of every device for days starting within [from, to).
+func (m *MemoryStore) Totals(from, to time.Time) ([]Total, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var totals []Total
+	for _, t := range m.totals {
+		if !t.Day.Before(from) && t.Day.Before(to) {
+			totals = append(totals, *t)
+		}
+	}
+	sortTotals(totals)
+	return totals, nil
+}
+
+func sortTotals(totals []Total) {
+	sort.Slice(totals, func(i, j int) bool {
+		if !totals[i].Day.Equal(totals[j].Day) {
+			return totals[i].Day.Before(totals[j].Day)
+		}
+		return totals[i].DeviceID < totals[j].DeviceID
+	})
+}
+
+// WriteCSV exports totals as CSV with a header row. Durations are written in
+// minutes and duty cycles as fractions of the day.
+func WriteCSV(w io.Writer, totals []Total) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"device_id", "date", "heating_minutes", "cooling_minutes", "heating_duty_cycle", "cooling_duty_cycle"}); err != nil {
+		return err
+	}
+	for _, t := range totals {
+		heating, cooling := t.DutyCycle()
+		err := cw.Write([]string{
+			t.DeviceID,
+			t.Day.Format("2006-01-02"),
+			strconv.FormatFloat(t.Heating.Minutes(), 'f', 1, 64),
+			strconv.FormatFloat(t.Cooling.Minutes(), 'f', 1, 64),
+			strconv.FormatFloat(heating, 'f', 4, 64),
+			strconv.FormatFloat(cooling, 'f', 4, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
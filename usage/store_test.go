@@ -0,0 +1,34 @@
+package usage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MemoryStoreTotals(t *testing.T) {
+	store := NewMemoryStore()
+	day := time.Date(2020, 3, 24, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, store.Add("2", day, device.HvacStateHeating, time.Hour))
+	assert.NoError(t, store.Add("1", day, device.HvacStateHeating, time.Hour))
+	assert.NoError(t, store.Add("1", day, device.HvacStateHeating, time.Hour))
+	assert.NoError(t, store.Add("1", day.AddDate(0, 0, 1), device.HvacStateCooling, time.Hour))
+
+	totals, err := store.Totals(day, day.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, []Total{
+		{DeviceID: "1", Day: day, Heating: 2 * time.Hour},
+		{DeviceID: "2", Day: day, Heating: time.Hour},
+	}, totals)
+}
+
+func Test_WriteCSV(t *testing.T) {
+	day := time.Date(2020, 3, 24, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []Total{{DeviceID: "1", Day: day, Heating: 6 * time.Hour, Cooling: 90 * time.Second}})
+	assert.NoError(t, err)
+	assert.Equal(t, "device_id,date,heating_minutes,cooling_minutes,heating_duty_cycle,cooling_duty_cycle\n1,2020-03-24,360.0,1.5,0.2500,0.0010\n", buf.String())
+}
@@ -0,0 +1,119 @@
+// Package usage accounts for HVAC runtime. The Nest API only reports whether a
+// thermostat is currently heating or cooling, so the Tracker derives per-day
+// heating and cooling minutes from hvac_state transitions.
+package usage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/device"
+)
+
+type observation struct {
+	state device.HvacState
+	since time.Time
+}
+
+// Tracker accumulates heating and cooling runtime per device and day. Days
+// are calendar days in the tracker's location; runtime spanning midnight is
+// split between both days.
+type Tracker struct {
+	store Store
+	loc   *time.Location
+	now   func() time.Time
+
+	mu   sync.Mutex
+	last map[string]observation
+}
+
+// NewTracker creates a tracker that records totals in store. A nil loc uses UTC.
+func NewTracker(store Store, loc *time.Location) *Tracker {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Tracker{
+		store: store,
+		loc:   loc,
+		now:   time.Now,
+		last:  map[string]observation{},
+	}
+}
+
+// Observe records the HVAC state of a device at the given time. The runtime
+// since the previous observation is attributed to the previous state.
+func (t *Tracker) Observe(deviceID string, state device.HvacState, at time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, ok := t.last[deviceID]
+	if ok && prev.state == state {
+		return nil
+	}
+	t.last[deviceID] = observation{state: state, since: at}
+	if !ok {
+		return nil
+	}
+	return t.record(deviceID, prev, at)
+}
+
+// Flush records the runtime of every device up to the given time without
+// waiting for the next state change, e.g. before reading today's totals.
+func (t *Tracker) Flush(at time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, prev := range t.last {
+		if !at.After(prev.since) {
+			continue
+		}
+		if err := t.record(id, prev, at); err != nil {
+			return err
+		}
+		t.last[id] = observation{state: prev.state, since: at}
+	}
+	return nil
+}
+
+// Consume observes thermostat events from a Stream until events is closed.
+// Events are timestamped when they are received.
+func (t *Tracker) Consume(events <-chan nest.Event) error {
+	for event := range events {
+		et, id, data, err := event.GetEvent()
+		if err != nil || et != nest.Thermostats {
+			continue
+		}
+		th, ok := data.(*device.Thermostat)
+		if !ok || th == nil || th.HvacState == "" {
+			continue
+		}
+		if th.DeviceID != "" {
+			id = th.DeviceID
+		}
+		if err := t.Observe(id, th.HvacState, t.now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record stores the runtime between prev.since and end, split by day.
+func (t *Tracker) record(deviceID string, prev observation, end time.Time) error {
+	if prev.state != device.HvacStateHeating && prev.state != device.HvacStateCooling {
+		return nil
+	}
+	start := prev.since.In(t.loc)
+	end = end.In(t.loc)
+	for start.Before(end) {
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, t.loc)
+		next := day.AddDate(0, 0, 1)
+		segment := end
+		if next.Before(end) {
+			segment = next
+		}
+		if err := t.store.Add(deviceID, day, prev.state, segment.Sub(start)); err != nil {
+			return err
+		}
+		start = segment
+	}
+	return nil
+}
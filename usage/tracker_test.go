@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtsiros/nest"
+	"github.com/jtsiros/nest/config"
+	"github.com/jtsiros/nest/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ObserveSplitsDays(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	store := NewMemoryStore()
+	tr := NewTracker(store, loc)
+
+	start := time.Date(2020, 3, 24, 23, 0, 0, 0, loc)
+	assert.NoError(t, tr.Observe("1", device.HvacStateHeating, start))
+	assert.NoError(t, tr.Observe("1", device.HvacStateHeating, start.Add(30*time.Minute)))
+	assert.NoError(t, tr.Observe("1", device.HvacStateOff, start.Add(90*time.Minute)))
+	assert.NoError(t, tr.Observe("1", device.HvacStateCooling, start.Add(10*time.Hour)))
+	assert.NoError(t, tr.Observe("1", device.HvacStateOff, start.Add(11*time.Hour)))
+
+	totals, err := store.Totals(time.Date(2020, 3, 24, 0, 0, 0, 0, loc), time.Date(2020, 3, 26, 0, 0, 0, 0, loc))
+	assert.NoError(t, err)
+	assert.Len(t, totals, 2)
+	assert.Equal(t, time.Hour, totals[0].Heating)
+	assert.Equal(t, 30*time.Minute, totals[1].Heating)
+	assert.Equal(t, time.Hour, totals[1].Cooling)
+
+	heating, cooling := totals[1].DutyCycle()
+	assert.InDelta(t, 0.5/24, heating, 1e-9)
+	assert.InDelta(t, 1.0/24, cooling, 1e-9)
+}
+
+func Test_Flush(t *testing.T) {
+	store := NewMemoryStore()
+	tr := NewTracker(store, nil)
+
+	start := time.Date(2020, 3, 24, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, tr.Observe("1", device.HvacStateCooling, start))
+	assert.NoError(t, tr.Flush(start.Add(time.Hour)))
+	assert.NoError(t, tr.Observe("1", device.HvacStateOff, start.Add(2*time.Hour)))
+
+	totals, _ := store.Totals(start.Add(-24*time.Hour), start.Add(24*time.Hour))
+	assert.Len(t, totals, 1)
+	assert.Equal(t, 2*time.Hour, totals[0].Cooling, "flushing should not double count runtime")
+}
+
+func Test_Consume(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, state := range []string{"heating", "off"} {
+			fmt.Fprintf(w, "event: put\ndata: {\"path\":\"/devices/thermostats/1\",\"data\":{\"device_id\":\"1\",\"hvac_state\":\"%s\"}}\n", state)
+		}
+		fmt.Fprint(w, "event: keep-alive\ndata: null\n")
+	}))
+	defer ts.Close()
+
+	s, _ := nest.NewStream(&config.Config{APIURL: ts.URL}, ts.Client())
+	events, err := s.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore()
+	tr := NewTracker(store, nil)
+	clock := time.Date(2020, 3, 24, 10, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time {
+		clock = clock.Add(15 * time.Minute)
+		return clock
+	}
+	assert.NoError(t, tr.Consume(events))
+
+	totals, _ := store.Totals(clock.Add(-24*time.Hour), clock.Add(24*time.Hour))
+	assert.Len(t, totals, 1)
+	assert.Equal(t, 15*time.Minute, totals[0].Heating)
+}